@@ -1,30 +1,17 @@
 package main
 
 import (
-	"fmt"
+	"flag"
 	"go/build"
-	_ "image/png"
 	"log"
 	"os"
 	"runtime"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
-	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/mrbeskin/shader-learning/render"
 )
 
-var (
-	texture uint32
-)
-
-const (
-	sizeof_float32 = 4
-)
-
-var (
-	VAO uint32
-	VBO uint32
-	EBO uint32
-)
+const title = "hello-rectangle"
 
 func init() {
 	// GLFW event handling must run on the main OS thread
@@ -32,26 +19,71 @@ func init() {
 }
 
 func main() {
+	render.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 
-	window := initGlfwWindow()
-	shaders := NewShaders("shader.vert", "shader.frag")
-	program := NewProgram(shaders)
-	version := gl.GoStr(gl.GetString(gl.VERSION))
-	fmt.Println("OpenGL version", version)
-	gl.UseProgram(program.glProgram)
-	defer func() { destroyScene() }()
+	window, err := render.NewWindow(800, 600, title)
+	if err != nil {
+		log.Fatalln("failed to open window:", err)
+	}
+	defer window.Destroy()
+	window.PrintVersion()
 
-	initBuffers()
+	shaders, err := render.NewShaders("shader.vert", "shader.frag")
+	if err != nil {
+		log.Fatalln("failed to load shaders:", err)
+	}
+	watcher, err := render.NewWatcher(render.DefaultDebounce)
+	if err != nil {
+		log.Fatalln("failed to start file watcher:", err)
+	}
+	defer watcher.Close()
+	if err := shaders.Watch(watcher); err != nil {
+		log.Fatalln("failed to watch shaders:", err)
+	}
+	program, err := render.NewProgram(shaders)
+	if err != nil {
+		log.Fatalln("failed to build program:", err)
+	}
+	program.PrintWarnings()
+
+	input, err := render.NewInput(window)
+	if err != nil {
+		log.Fatalln("failed to set up input:", err)
+	}
+	input.OnAction("quit", func() { window.SetShouldClose(true) })
+	input.OnAction("wireframe", window.ToggleWireframe)
+	input.OnAction("screenshot", window.CaptureNext)
+	input.OnAction("reload", func() {
+		if err := program.Reload(); err != nil {
+			log.Println("failed to reload shaders, keeping last good program:", err)
+		}
+	})
+
+	mesh, err := render.NewMesh(vertices, indices, render.FloatLayout(3))
+	if err != nil {
+		log.Fatalln("failed to create mesh:", err)
+	}
+	gl.ClearColor(0.2, 0.3, 0.3, 1.0)
 
 	for !(window.ShouldClose()) {
-		program.UpdateShaders()
+		hadErr := program.Err() != nil
+		input.Update()
+		if err := program.UpdateShaders(); err != nil {
+			log.Println("failed to reload shaders, keeping last good program:", err)
+		}
+		// flag a broken shader in the title until it is fixed
+		if hasErr := program.Err() != nil; hasErr != hadErr {
+			if hasErr {
+				window.SetTitle(title + " (shader error)")
+			} else {
+				window.SetTitle(title)
+			}
+		}
 		gl.Clear(gl.COLOR_BUFFER_BIT)
-		gl.UseProgram(program.glProgram)
-		gl.BindVertexArray(VAO)
-		gl.DrawElements(gl.TRIANGLES, 6, gl.UNSIGNED_INT, gl.PtrOffset(0))
-		gl.BindVertexArray(0)
-		window.SwapBuffers()
-		glfw.PollEvents()
+		program.Use()
+		mesh.Draw()
+		window.Update()
 	}
 }
 
@@ -68,53 +100,6 @@ var indices = []uint32{
 	1, 2, 3,
 }
 
-func destroyScene() {
-	defer glfw.Terminate()
-	gl.Flush()
-}
-
-func initGlfwWindow() *glfw.Window {
-	// initialize glfw window
-	if err := glfw.Init(); err != nil {
-		log.Fatalln("failed to initialize glfw:", err)
-	}
-
-	glfw.WindowHint(glfw.ContextVersionMajor, 3)
-	glfw.WindowHint(glfw.ContextVersionMinor, 2)
-	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
-	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
-	window, err := glfw.CreateWindow(800, 600, "hello-rectangle", nil, nil)
-	if err != nil {
-		panic(err)
-	}
-	window.MakeContextCurrent()
-	window.SetFramebufferSizeCallback(glfw.FramebufferSizeCallback(fbcallback))
-	return window
-}
-
-func initBuffers() {
-	gl.GenVertexArrays(1, &VAO)
-	gl.GenBuffers(1, &VBO)
-	gl.GenBuffers(1, &EBO)
-
-	// bind Vertex Array first
-	gl.BindVertexArray(VAO)
-	gl.BindBuffer(gl.ARRAY_BUFFER, VBO)
-	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*sizeof_float32, gl.Ptr(vertices), gl.STATIC_DRAW)
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, EBO)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(indices)*sizeof_float32, gl.Ptr(indices), gl.STATIC_DRAW)
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 3*sizeof_float32, gl.PtrOffset(0))
-	gl.EnableVertexAttribArray(0)
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
-
-	gl.BindVertexArray(0)
-	gl.ClearColor(0.2, 0.3, 0.3, 1.0)
-}
-
-func fbcallback(w *glfw.Window, width int, height int) {
-	gl.Viewport(0, 0, int32(width), int32(height))
-}
-
 func init() {
 	dir, err := importPathToDir("github.com/mrbeskin/shader-learning/3-rect-reloadable-shaders")
 	if err != nil {
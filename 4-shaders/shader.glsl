@@ -1,4 +1,6 @@
 #version 330 core
+
+#pragma stage vertex
 layout (location = 0) in vec3 aPos;
 layout (location = 1) in vec3 aColor;
 
@@ -9,3 +11,12 @@ void main() {
     newColor = aColor;
 }
 
+#pragma stage fragment
+out vec4 FragColor;
+in vec3 newColor;
+
+uniform vec4 pulseColor;
+
+void main() {
+    FragColor = mix(vec4(newColor, 1.0), pulseColor, 0.5);
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mrbeskin/shader-learning/render/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Chapter(t, main)
+}
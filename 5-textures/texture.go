@@ -1,56 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"github.com/go-gl/gl/v4.1-core/gl"
-	"image"
-	"image/draw"
-	_ "image/jpeg"
-	"os"
-)
-
-type Texture struct {
-	ID   uint32
-	Path string
-}
-
-func NewTexture(path string) *Texture {
-
-	imgFile, err := os.Open(path)
-	check("opening image file for texture", err)
-
-	img, _, err := image.Decode(imgFile)
-	check("decoding image for texture", err)
-
-	rgba := image.NewRGBA(img.Bounds())
-	if rgba.Stride != rgba.Rect.Size().X*4 {
-		check("decoding image", fmt.Errorf("unsupported image stride"))
-	}
-
-	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0, 0}, draw.Src)
-
-	var id uint32
-	gl.GenTextures(1, &id)
-	gl.ActiveTexture(gl.TEXTURE0)
-	gl.BindTexture(gl.TEXTURE_2D, id)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	gl.TexImage2D(
-		gl.TEXTURE_2D,
-		0,
-		gl.RGBA,
-		int32(rgba.Rect.Size().X),
-		int32(rgba.Rect.Size().Y),
-		0,
-		gl.RGBA,
-		gl.UNSIGNED_BYTE,
-		gl.Ptr(rgba.Pix))
-	gl.GenerateMipmap(gl.TEXTURE_2D)
-
-	return &Texture{
-		ID:   id,
-		Path: path,
-	}
-}
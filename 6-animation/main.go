@@ -1,30 +1,15 @@
 package main
 
 import (
-	"fmt"
+	"flag"
 	"go/build"
-	_ "image/png"
 	"log"
 	"os"
 	"runtime"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
-	"github.com/go-gl/glfw/v3.2/glfw"
 	"github.com/go-gl/mathgl/mgl32"
-)
-
-var (
-	texture uint32
-)
-
-const (
-	sizeof_float32 = 4
-)
-
-var (
-	VAO uint32
-	VBO uint32
-	EBO uint32
+	"github.com/mrbeskin/shader-learning/render"
 )
 
 func init() {
@@ -33,47 +18,99 @@ func init() {
 }
 
 func main() {
+	render.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 
-	window := initGlfwWindow()
-	if err := gl.Init(); err != nil {
-		check("initializing gl", err)
+	window, err := render.NewWindow(800, 600, "hello-rectangle")
+	if err != nil {
+		log.Fatalln("failed to open window:", err)
+	}
+	defer window.Destroy()
+	window.PrintVersion()
+
+	shader, err := render.NewShader("shader.frag", "shader.vert")
+	if err != nil {
+		log.Fatalln("failed to build shader:", err)
 	}
-	shader := NewShader("shader.frag", "shader.vert")
-	version := gl.GoStr(gl.GetString(gl.VERSION))
-	fmt.Println("OpenGL version", version)
-	defer func() { destroyScene() }()
+	shader.PrintWarnings()
 
-	initBuffers()
+	if err := layout.Check(shader); err != nil {
+		log.Fatalln("vertex layout does not match shader:", err)
+	}
+	mesh, err := render.NewMesh(vertices, indices, layout)
+	if err != nil {
+		log.Fatalln("failed to create mesh:", err)
+	}
 	gl.ActiveTexture(gl.TEXTURE0)
-	tx1 := NewTexture("container.jpg")
-	tx2 := NewTexture("awesomeface.png")
+	tx1, err := render.NewTexture("container.jpg")
+	if err != nil {
+		log.Fatalln("failed to load texture:", err)
+	}
+	tx2, err := render.NewTexture("awesomeface.png")
+	if err != nil {
+		log.Fatalln("failed to load texture:", err)
+	}
+
+	// reload textures in place when their files change
+	watcher, err := render.NewWatcher(render.DefaultDebounce)
+	if err != nil {
+		log.Fatalln("failed to start file watcher:", err)
+	}
+	defer watcher.Close()
+	assets := render.NewAssets(watcher)
+	for _, tx := range []*render.Texture{tx1, tx2} {
+		if err := assets.Add(tx); err != nil {
+			log.Fatalln("failed to watch texture:", err)
+		}
+	}
 
 	shader.Use()
-	gl.Uniform1i(gl.GetUniformLocation(tx1.ID, gl.Str("texture1\x00")), 0)
-	shader.SetInt("texture2\x00", 1)
+	shader.SetSampler("texture1", 0)
+	shader.SetSampler("texture2", 1)
+
+	// a quad spinning in the bottom right corner, with a smaller quad
+	// orbiting it
+	quad := render.NewTransform()
+	quad.Position = mgl32.Vec3{0.5, -0.5, 0.0}
+	moon := render.NewTransform()
+	moon.Position = mgl32.Vec3{0.0, 0.8, 0.0}
+	moon.Scale = mgl32.Vec3{0.4, 0.4, 1.0}
+	quad.AddChild(moon)
+
+	input, err := render.NewInput(window)
+	if err != nil {
+		log.Fatalln("failed to set up input:", err)
+	}
+	input.OnAction("quit", func() { window.SetShouldClose(true) })
+	input.OnAction("wireframe", window.ToggleWireframe)
+	input.OnAction("screenshot", window.CaptureNext)
+	clock := render.NewClock()
+	input.OnAction("pause", clock.TogglePause)
 
 	for !(window.ShouldClose()) {
+		input.Update()
+		clock.Tick()
+
+		for _, err := range assets.Update() {
+			log.Println("failed to reload asset:", err)
+		}
 
 		gl.ClearColor(0.2, 0.3, 0.3, 1.0)
 		gl.Clear(gl.COLOR_BUFFER_BIT)
 
-		gl.ActiveTexture(gl.TEXTURE0)
-		gl.BindTexture(gl.TEXTURE_2D, tx1.ID)
-
-		gl.ActiveTexture(gl.TEXTURE1)
-		gl.BindTexture(gl.TEXTURE_2D, tx2.ID)
+		tx1.Bind(gl.TEXTURE0)
+		tx2.Bind(gl.TEXTURE1)
 
-		// Create transformation
-		var transform glm32.Mat4
-		transform = glm32.translate(transform, glm32.Vec3(0.5, -0.5, 0.0))
-		transform = glm32.rotate(transform, float(glfw.GetTime()), glm32.Vec3(0.0, 0.0, 1.0))
+		elapsed := float32(clock.Time())
+		quad.Rotation = mgl32.QuatRotate(elapsed, mgl32.Vec3{0.0, 0.0, 1.0})
+		moon.Rotation = mgl32.QuatRotate(-2*elapsed, mgl32.Vec3{0.0, 0.0, 1.0})
 
 		shader.Use()
-		gl.BindVertexArray(VAO)
-		gl.DrawElements(gl.TRIANGLES, 6, gl.UNSIGNED_INT, gl.PtrOffset(0))
-
-		window.SwapBuffers()
-		glfw.PollEvents()
+		for _, transform := range []*render.Transform{quad, moon} {
+			shader.SetMat4("transform", transform.World())
+			mesh.Draw()
+		}
+		window.Update()
 	}
 }
 
@@ -85,67 +122,20 @@ var vertices = []float32{
 	-0.5, 0.5, 0.0, 1.0, 0.0, 0.5, 0.0, 1.0, // top left
 }
 
+// layout interleaves position, color and texture coordinates
+var layout = render.NewVertexLayout(
+	render.VertexAttribute{Name: "aPos", Location: 0, Count: 3, Type: gl.FLOAT},
+	render.VertexAttribute{Name: "aColor", Location: 1, Count: 3, Type: gl.FLOAT},
+	render.VertexAttribute{Name: "aTexCoord", Location: 2, Count: 2, Type: gl.FLOAT},
+)
+
 var indices = []uint32{
 	0, 1, 3,
 	1, 2, 3,
 }
 
-func destroyScene() {
-	defer glfw.Terminate()
-	gl.Flush()
-}
-
-func initGlfwWindow() *glfw.Window {
-	// initialize glfw window
-	if err := glfw.Init(); err != nil {
-		log.Fatalln("failed to initialize glfw:", err)
-	}
-
-	glfw.WindowHint(glfw.ContextVersionMajor, 3)
-	glfw.WindowHint(glfw.ContextVersionMinor, 2)
-	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
-	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
-	window, err := glfw.CreateWindow(800, 600, "hello-rectangle", nil, nil)
-	if err != nil {
-		panic(err)
-	}
-	window.MakeContextCurrent()
-	window.SetFramebufferSizeCallback(glfw.FramebufferSizeCallback(fbcallback))
-	return window
-}
-
-func initBuffers() {
-	gl.GenVertexArrays(1, &VAO)
-	gl.GenBuffers(1, &VBO)
-	gl.GenBuffers(1, &EBO)
-
-	// bind Vertex Array first
-	gl.BindVertexArray(VAO)
-	gl.BindBuffer(gl.ARRAY_BUFFER, VBO)
-	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*sizeof_float32, gl.Ptr(vertices), gl.STATIC_DRAW)
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, EBO)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(indices)*sizeof_float32, gl.Ptr(indices), gl.STATIC_DRAW)
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 8*sizeof_float32, gl.PtrOffset(0))
-	gl.EnableVertexAttribArray(0)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(indices)*sizeof_float32, gl.Ptr(indices), gl.STATIC_DRAW)
-	gl.VertexAttribPointer(1, 3, gl.FLOAT, false, 8*sizeof_float32, gl.PtrOffset(3*sizeof_float32))
-	gl.EnableVertexAttribArray(1)
-
-	gl.VertexAttribPointer(2, 2, gl.FLOAT, false, 8*sizeof_float32, gl.PtrOffset(6*sizeof_float32))
-	gl.EnableVertexAttribArray(2)
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
-
-	gl.BindVertexArray(0)
-	gl.ClearColor(0.2, 0.3, 0.3, 1.0)
-}
-
-func fbcallback(w *glfw.Window, width int, height int) {
-	gl.Viewport(0, 0, int32(width), int32(height))
-}
-
 func init() {
-	dir, err := importPathToDir("github.com/mrbeskin/shader-learning/5-textures-pt2")
+	dir, err := importPathToDir("github.com/mrbeskin/shader-learning/6-animation")
 	if err != nil {
 		log.Fatalln("could not locate assets on GOPATH:", err)
 	}
@@ -1,7 +1,6 @@
 #version 330 core
 out vec4 FragColor;
 
-in vec3 newColor;
 in vec2 TexCoord;
 
 uniform sampler2D texture1;
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"go/build"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/mrbeskin/shader-learning/render"
+)
+
+func init() {
+	// GLFW event handling must run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	render.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	window, err := render.NewWindow(800, 600, "hello-camera")
+	if err != nil {
+		log.Fatalln("failed to open window:", err)
+	}
+	defer window.Destroy()
+	window.PrintVersion()
+
+	shader, err := render.NewShaderFromFile("shader.glsl")
+	if err != nil {
+		log.Fatalln("failed to build shader:", err)
+	}
+	shader.PrintWarnings()
+
+	camera := render.NewPerspectiveCamera(mgl32.DegToRad(45), 800.0/600.0, 0.1, 100.0)
+	camera.SetViewport(window.GetFramebufferSize())
+	window.OnResize(camera.SetViewport)
+	camera.Position = mgl32.Vec3{0, 0, 3}
+	// WASD, Space and Left Shift fly; drag with the right mouse button to look
+	controller := render.NewFlyController(camera)
+
+	if err := layout.Check(shader); err != nil {
+		log.Fatalln("vertex layout does not match shader:", err)
+	}
+	mesh, err := render.NewMesh(vertices, indices, layout)
+	if err != nil {
+		log.Fatalln("failed to create mesh:", err)
+	}
+	cube := render.NewTransform()
+	gl.Enable(gl.DEPTH_TEST)
+	gl.ClearColor(0.2, 0.3, 0.3, 1.0)
+
+	input, err := render.NewInput(window)
+	if err != nil {
+		log.Fatalln("failed to set up input:", err)
+	}
+	input.OnAction("quit", func() { window.SetShouldClose(true) })
+	input.OnAction("wireframe", window.ToggleWireframe)
+	input.OnAction("screenshot", window.CaptureNext)
+
+	clock := render.NewClock()
+	for !(window.ShouldClose()) {
+		input.Update()
+		clock.Tick()
+		controller.Update(window, float32(clock.Delta()))
+
+		cube.Rotation = mgl32.QuatRotate(float32(clock.Time())*0.5, mgl32.Vec3{0.5, 1.0, 0.0}.Normalize())
+
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+		shader.Use()
+		shader.SetMat4("model", cube.World())
+		shader.SetMat4("view", camera.View())
+		shader.SetMat4("projection", camera.ProjectionMatrix())
+		mesh.Draw()
+		window.Update()
+	}
+}
+
+var vertices = []float32{
+	// position, color
+	-0.5, -0.5, -0.5, 0.0, 0.0, 0.0,
+	0.5, -0.5, -0.5, 1.0, 0.0, 0.0,
+	0.5, 0.5, -0.5, 1.0, 1.0, 0.0,
+	-0.5, 0.5, -0.5, 0.0, 1.0, 0.0,
+	-0.5, -0.5, 0.5, 0.0, 0.0, 1.0,
+	0.5, -0.5, 0.5, 1.0, 0.0, 1.0,
+	0.5, 0.5, 0.5, 1.0, 1.0, 1.0,
+	-0.5, 0.5, 0.5, 0.0, 1.0, 1.0,
+}
+
+// layout interleaves position and color
+var layout = render.NewVertexLayout(
+	render.VertexAttribute{Name: "aPos", Location: 0, Count: 3, Type: gl.FLOAT},
+	render.VertexAttribute{Name: "aColor", Location: 1, Count: 3, Type: gl.FLOAT},
+)
+
+var indices = []uint32{
+	0, 1, 2, 2, 3, 0, // back
+	4, 5, 6, 6, 7, 4, // front
+	0, 4, 7, 7, 3, 0, // left
+	1, 5, 6, 6, 2, 1, // right
+	0, 1, 5, 5, 4, 0, // bottom
+	3, 2, 6, 6, 7, 3, // top
+}
+
+func init() {
+	dir, err := importPathToDir("github.com/mrbeskin/shader-learning/7-camera")
+	if err != nil {
+		log.Fatalln("could not locate assets on GOPATH:", err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		log.Panicln("os.Chdir:", err)
+	}
+}
+
+func importPathToDir(importPath string) (string, error) {
+	p, err := build.Import(importPath, "", build.FindOnly)
+	if err != nil {
+		return "", err
+	}
+	return p.Dir, nil
+}
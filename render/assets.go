@@ -0,0 +1,162 @@
+package render
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// Asset is a gl resource loaded from files that can be reloaded in place.
+// Texture and Program are assets.
+type Asset interface {
+	// Paths returns the files the asset is built from. It may change after
+	// a reload, e.g. when a shader includes a new file.
+	Paths() []string
+	// Reload rebuilds the asset from its files. It is called on the thread
+	// that calls Assets.Update, so it may make gl calls.
+	Reload() error
+}
+
+// Assets reloads registered assets when the files they are built from
+// change. Changes are collected from a Watcher in the background and
+// applied by Update, which should be called once per frame from the
+// thread that owns the gl context.
+type Assets struct {
+	watcher *Watcher
+
+	mu      sync.Mutex
+	assets  map[string][]Asset
+	paths   map[Asset][]string
+	changed map[Asset]bool
+}
+
+// NewAssets returns an empty asset set fed by the given watcher
+func NewAssets(w *Watcher) *Assets {
+	return &Assets{
+		watcher: w,
+		assets:  map[string][]Asset{},
+		paths:   map[Asset][]string{},
+		changed: map[Asset]bool{},
+	}
+}
+
+// Add starts reloading an asset when any of its files change
+func (a *Assets) Add(asset Asset) error {
+	return a.track(asset)
+}
+
+// Remove stops reloading an asset. The watcher keeps watching its files.
+func (a *Assets) Remove(asset Asset) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, path := range a.paths[asset] {
+		a.assets[path] = withoutAsset(a.assets[path], asset)
+	}
+	delete(a.paths, asset)
+	delete(a.changed, asset)
+}
+
+// Update reloads the assets whose files changed since the last call and
+// returns the errors from any that failed. Failed assets keep their last
+// good state and are retried on their next change.
+func (a *Assets) Update() []error {
+	a.mu.Lock()
+	changed := a.changed
+	a.changed = map[Asset]bool{}
+	a.mu.Unlock()
+
+	var errs []error
+	for asset := range changed {
+		if err := asset.Reload(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := a.track(asset); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// track files the asset under its current paths, watching any new ones
+func (a *Assets) track(asset Asset) error {
+	var paths []string
+	for _, path := range asset.Paths() {
+		paths = appendUnique(paths, absPath(path))
+	}
+
+	a.mu.Lock()
+	old := a.paths[asset]
+	a.paths[asset] = paths
+	for _, path := range old {
+		if !containsFile(paths, path) {
+			a.assets[path] = withoutAsset(a.assets[path], asset)
+		}
+	}
+	var watch []string
+	for _, path := range paths {
+		if containsFile(old, path) {
+			continue
+		}
+		if _, watched := a.assets[path]; !watched {
+			watch = append(watch, path)
+		}
+		a.assets[path] = append(a.assets[path], asset)
+	}
+	a.mu.Unlock()
+
+	var err error
+	for _, path := range watch {
+		if werr := a.watcher.OnChange(path, a.onChange); werr != nil {
+			a.forget(asset, path)
+			if err == nil {
+				err = werr
+			}
+		}
+	}
+	return err
+}
+
+// forget undoes tracking a path that could not be watched, so the next
+// Add or reload of the asset tries to watch it again
+func (a *Assets) forget(asset Asset, path string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.assets, path)
+	paths, ok := a.paths[asset]
+	if !ok {
+		return
+	}
+	kept := paths[:0]
+	for _, p := range paths {
+		if p != path {
+			kept = append(kept, p)
+		}
+	}
+	a.paths[asset] = kept
+}
+
+func (a *Assets) onChange(c Change) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, asset := range a.assets[absPath(c.Path)] {
+		a.changed[asset] = true
+	}
+}
+
+func withoutAsset(assets []Asset, asset Asset) []Asset {
+	kept := assets[:0]
+	for _, other := range assets {
+		if other != asset {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
@@ -0,0 +1,262 @@
+package render
+
+import (
+	"math"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Projection is the kind of projection a Camera uses
+type Projection int
+
+const (
+	Perspective Projection = iota
+	Orthographic
+)
+
+// Camera produces view and projection matrices. It looks along the
+// direction given by Yaw and Pitch, in radians; a yaw of zero looks down -z.
+type Camera struct {
+	Position mgl32.Vec3
+	Yaw      float32
+	Pitch    float32
+	Up       mgl32.Vec3
+
+	Projection Projection
+	// FOV is the vertical field of view of a perspective camera, in radians
+	FOV float32
+	// Height is the height of the view volume of an orthographic camera
+	Height float32
+	Near   float32
+	Far    float32
+	// Aspect is width over height, kept up to date by SetViewport
+	Aspect float32
+}
+
+// NewPerspectiveCamera returns a perspective camera at the origin looking
+// down -z
+func NewPerspectiveCamera(fov float32, aspect float32, near float32, far float32) *Camera {
+	return &Camera{
+		Up:         mgl32.Vec3{0, 1, 0},
+		Projection: Perspective,
+		FOV:        fov,
+		Near:       near,
+		Far:        far,
+		Aspect:     aspect,
+	}
+}
+
+// NewOrthographicCamera returns an orthographic camera at the origin
+// looking down -z, seeing height units vertically
+func NewOrthographicCamera(height float32, aspect float32, near float32, far float32) *Camera {
+	return &Camera{
+		Up:         mgl32.Vec3{0, 1, 0},
+		Projection: Orthographic,
+		Height:     height,
+		Near:       near,
+		Far:        far,
+		Aspect:     aspect,
+	}
+}
+
+// SetViewport updates the aspect ratio from a framebuffer size. It can be
+// passed to Window.OnResize.
+func (c *Camera) SetViewport(width int, height int) {
+	if height > 0 {
+		c.Aspect = float32(width) / float32(height)
+	}
+}
+
+// Front returns the unit vector the camera looks along
+func (c *Camera) Front() mgl32.Vec3 {
+	yaw, pitch := float64(c.Yaw), float64(c.Pitch)
+	return mgl32.Vec3{
+		float32(math.Sin(yaw) * math.Cos(pitch)),
+		float32(math.Sin(pitch)),
+		float32(-math.Cos(yaw) * math.Cos(pitch)),
+	}
+}
+
+// Right returns the unit vector to the camera's right
+func (c *Camera) Right() mgl32.Vec3 {
+	return c.Front().Cross(c.Up).Normalize()
+}
+
+// LookAt points the camera at target
+func (c *Camera) LookAt(target mgl32.Vec3) {
+	dir := target.Sub(c.Position)
+	if dir.Len() == 0 {
+		return
+	}
+	dir = dir.Normalize()
+	c.Pitch = float32(math.Asin(float64(dir.Y())))
+	c.Yaw = float32(math.Atan2(float64(dir.X()), float64(-dir.Z())))
+}
+
+// View returns the view matrix
+func (c *Camera) View() mgl32.Mat4 {
+	return mgl32.LookAtV(c.Position, c.Position.Add(c.Front()), c.Up)
+}
+
+// ProjectionMatrix returns the projection matrix
+func (c *Camera) ProjectionMatrix() mgl32.Mat4 {
+	if c.Projection == Orthographic {
+		h := c.Height / 2
+		w := h * c.Aspect
+		return mgl32.Ortho(-w, w, -h, h, c.Near, c.Far)
+	}
+	return mgl32.Perspective(c.FOV, c.Aspect, c.Near, c.Far)
+}
+
+// ViewProjection returns the projection matrix times the view matrix
+func (c *Camera) ViewProjection() mgl32.Mat4 {
+	return c.ProjectionMatrix().Mul4(c.View())
+}
+
+// maxPitch keeps controllers just short of looking straight up or down,
+// where the view matrix flips
+const maxPitch = math.Pi/2 - 0.01
+
+func clampPitch(pitch float32) float32 {
+	return float32(math.Max(-maxPitch, math.Min(maxPitch, float64(pitch))))
+}
+
+// CameraController moves a camera from window input. Update should be
+// called once a frame with the seconds since the last frame.
+type CameraController interface {
+	Update(w *Window, dt float32)
+}
+
+// FlyController moves a camera like a free flying spectator: WASD moves,
+// Space and Left Shift go up and down, and dragging with the right mouse
+// button looks around
+type FlyController struct {
+	Camera *Camera
+	// Speed is in units per second
+	Speed float32
+	// Sensitivity is in radians per pixel of mouse movement
+	Sensitivity float32
+
+	drag dragState
+}
+
+// NewFlyController returns a fly controller with default speeds
+func NewFlyController(camera *Camera) *FlyController {
+	return &FlyController{
+		Camera:      camera,
+		Speed:       2.5,
+		Sensitivity: 0.003,
+	}
+}
+
+// Update moves the camera from the current keyboard and mouse state
+func (f *FlyController) Update(w *Window, dt float32) {
+	c := f.Camera
+	step := f.Speed * dt
+	front, right := c.Front(), c.Right()
+	moves := []struct {
+		key glfw.Key
+		dir mgl32.Vec3
+	}{
+		{glfw.KeyW, front},
+		{glfw.KeyS, front.Mul(-1)},
+		{glfw.KeyD, right},
+		{glfw.KeyA, right.Mul(-1)},
+		{glfw.KeySpace, c.Up},
+		{glfw.KeyLeftShift, c.Up.Mul(-1)},
+	}
+	for _, m := range moves {
+		if w.GetKey(m.key) == glfw.Press {
+			c.Position = c.Position.Add(m.dir.Mul(step))
+		}
+	}
+
+	if dx, dy, ok := f.drag.update(w, glfw.MouseButtonRight); ok {
+		c.Yaw += float32(dx) * f.Sensitivity
+		c.Pitch = clampPitch(c.Pitch - float32(dy)*f.Sensitivity)
+	}
+}
+
+// OrbitController keeps a camera looking at Target from Distance away:
+// dragging with the left mouse button orbits and W/S zoom in and out
+type OrbitController struct {
+	Camera   *Camera
+	Target   mgl32.Vec3
+	Distance float32
+	// Yaw and Pitch place the camera around the target, in radians
+	Yaw   float32
+	Pitch float32
+	// ZoomSpeed is in units per second
+	ZoomSpeed float32
+	// Sensitivity is in radians per pixel of mouse movement
+	Sensitivity float32
+
+	drag dragState
+}
+
+// NewOrbitController returns an orbit controller looking at target from
+// distance away
+func NewOrbitController(camera *Camera, target mgl32.Vec3, distance float32) *OrbitController {
+	o := &OrbitController{
+		Camera:      camera,
+		Target:      target,
+		Distance:    distance,
+		ZoomSpeed:   2.5,
+		Sensitivity: 0.005,
+	}
+	o.place()
+	return o
+}
+
+// Update orbits and zooms the camera from the current keyboard and mouse
+// state
+func (o *OrbitController) Update(w *Window, dt float32) {
+	if w.GetKey(glfw.KeyW) == glfw.Press {
+		o.Distance -= o.ZoomSpeed * dt
+	}
+	if w.GetKey(glfw.KeyS) == glfw.Press {
+		o.Distance += o.ZoomSpeed * dt
+	}
+	if o.Distance < 0.1 {
+		o.Distance = 0.1
+	}
+	if dx, dy, ok := o.drag.update(w, glfw.MouseButtonLeft); ok {
+		o.Yaw -= float32(dx) * o.Sensitivity
+		o.Pitch = clampPitch(o.Pitch + float32(dy)*o.Sensitivity)
+	}
+	o.place()
+}
+
+// place moves the camera onto the sphere around the target
+func (o *OrbitController) place() {
+	yaw, pitch := float64(o.Yaw), float64(o.Pitch)
+	offset := mgl32.Vec3{
+		float32(math.Sin(yaw) * math.Cos(pitch)),
+		float32(math.Sin(pitch)),
+		float32(math.Cos(yaw) * math.Cos(pitch)),
+	}
+	o.Camera.Position = o.Target.Add(offset.Mul(o.Distance))
+	o.Camera.LookAt(o.Target)
+}
+
+// dragState tracks the cursor while a mouse button is held
+type dragState struct {
+	dragging bool
+	x, y     float64
+}
+
+// update returns how far the cursor moved since the last call while button
+// was held
+func (d *dragState) update(w *Window, button glfw.MouseButton) (dx float64, dy float64, ok bool) {
+	if w.GetMouseButton(button) != glfw.Press {
+		d.dragging = false
+		return 0, 0, false
+	}
+	x, y := w.GetCursorPos()
+	if d.dragging {
+		dx, dy, ok = x-d.x, y-d.y, true
+	}
+	d.dragging, d.x, d.y = true, x, y
+	return
+}
@@ -0,0 +1,171 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// ReadPixels reads the color buffer of a framebuffer, or of the default
+// framebuffer's back buffer if fb is 0. gl stores rows bottom up, so the
+// rows are flipped to put the top of the image first.
+func ReadPixels(fb uint32, width int, height int) *image.RGBA {
+	bindForRead(fb)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	flipRows(img.Pix, img.Stride, height)
+	return img
+}
+
+// FloatImage is an image of linear float RGBA pixels, read from a float
+// render target
+type FloatImage struct {
+	Width, Height int
+	// Pix holds 4 floats per pixel, top row first
+	Pix []float32
+}
+
+// ReadPixelsFloat reads the color buffer of a framebuffer as floats, for
+// HDR render targets. The rows are flipped like ReadPixels.
+func ReadPixelsFloat(fb uint32, width int, height int) *FloatImage {
+	bindForRead(fb)
+	img := &FloatImage{Width: width, Height: height, Pix: make([]float32, width*height*4)}
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 4)
+	gl.ReadPixels(0, 0, int32(width), int32(height), gl.RGBA, gl.FLOAT, gl.Ptr(img.Pix))
+	row := width * 4
+	for top, bottom := 0, height-1; top < bottom; top, bottom = top+1, bottom-1 {
+		for i := 0; i < row; i++ {
+			a, b := top*row+i, bottom*row+i
+			img.Pix[a], img.Pix[b] = img.Pix[b], img.Pix[a]
+		}
+	}
+	return img
+}
+
+func bindForRead(fb uint32) {
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, fb)
+	if fb == 0 {
+		gl.ReadBuffer(gl.BACK)
+	} else {
+		gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	}
+}
+
+func flipRows(pix []uint8, stride int, height int) {
+	tmp := make([]uint8, stride)
+	for top, bottom := 0, height-1; top < bottom; top, bottom = top+1, bottom-1 {
+		a, b := pix[top*stride:(top+1)*stride], pix[bottom*stride:(bottom+1)*stride]
+		copy(tmp, a)
+		copy(a, b)
+		copy(b, tmp)
+	}
+}
+
+// SavePNG encodes img as a PNG file
+func SavePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SaveHDR encodes img as a Radiance RGBE (.hdr) file, keeping values above 1
+func SaveHDR(path string, img *FloatImage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y %d +X %d\n", img.Height, img.Width)
+	for i := 0; i < img.Width*img.Height; i++ {
+		p := img.Pix[i*4 : i*4+3]
+		w.Write(rgbe(p[0], p[1], p[2]))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rgbe packs a color into a shared exponent, as in Greg Ward's float2rgbe
+func rgbe(r float32, g float32, b float32) []byte {
+	v := math.Max(float64(r), math.Max(float64(g), float64(b)))
+	if v < 1e-32 {
+		return []byte{0, 0, 0, 0}
+	}
+	frac, exp := math.Frexp(v)
+	scale := frac * 256 / v
+	return []byte{
+		byte(math.Max(0, float64(r)*scale)),
+		byte(math.Max(0, float64(g)*scale)),
+		byte(math.Max(0, float64(b)*scale)),
+		byte(exp + 128),
+	}
+}
+
+// Screenshot reads what has been drawn this frame. It must be called
+// before Update swaps the buffers.
+func (w *Window) Screenshot() *image.RGBA {
+	width, height := w.GetFramebufferSize()
+	return ReadPixels(w.framebufferID(), width, height)
+}
+
+// SaveScreenshot saves what has been drawn this frame as a PNG, or as an
+// HDR file if path ends in .hdr. It must be called before Update swaps the
+// buffers.
+func (w *Window) SaveScreenshot(path string) error {
+	if strings.EqualFold(filepath.Ext(path), ".hdr") {
+		width, height := w.GetFramebufferSize()
+		return SaveHDR(path, ReadPixelsFloat(w.framebufferID(), width, height))
+	}
+	return SavePNG(path, w.Screenshot())
+}
+
+// framebufferID returns the framebuffer the window draws into
+func (w *Window) framebufferID() uint32 {
+	if w.Headless() {
+		return w.framebuffer.id
+	}
+	return 0
+}
+
+// CaptureNext saves a screenshot of the current frame when Update is
+// called, to the -capture-path file. It can be bound to an input action,
+// e.g. input.OnAction("screenshot", window.CaptureNext).
+func (w *Window) CaptureNext() {
+	w.captureNext = true
+}
+
+// capture saves a screenshot if one was asked for with CaptureNext or the
+// -capture-frame flag
+func (w *Window) capture() {
+	frame := w.frame + 1
+	if !w.captureNext && frame != settings.captureFrame {
+		return
+	}
+	w.captureNext = false
+	path := settings.capturePath
+	if strings.Contains(path, "%d") {
+		path = fmt.Sprintf(path, frame)
+	}
+	if err := w.SaveScreenshot(path); err != nil {
+		log.Println("failed to save screenshot:", err)
+		return
+	}
+	log.Println("saved screenshot", path)
+}
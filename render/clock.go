@@ -0,0 +1,160 @@
+package render
+
+import (
+	"time"
+)
+
+// Clock is the time source for animation. It advances once a frame with
+// Tick, and can be paused, scaled and scrubbed without affecting the wall
+// clock. A fixed clock advances by the same step every frame, so a run
+// can be replayed frame for frame.
+type Clock struct {
+	// Scale multiplies the time that passes, e.g. 0.5 for half speed
+	Scale float64
+	// FixedStep, if set, is the time each Tick advances by in place of the
+	// wall clock time since the last Tick
+	FixedStep float64
+
+	now         func() float64
+	last        float64
+	time        float64
+	delta       float64
+	frame       int
+	paused      bool
+	accumulator float64
+}
+
+// NewClock returns a clock driven by the wall clock. If the -time flag is
+// set the clock starts paused at that time.
+func NewClock() *Clock {
+	c := &Clock{
+		Scale: 1,
+		now:   wallSeconds(),
+	}
+	c.last = c.now()
+	c.freeze()
+	return c
+}
+
+// NewFixedClock returns a clock that advances by step seconds every frame,
+// regardless of how long frames take
+func NewFixedClock(step float64) *Clock {
+	c := &Clock{
+		Scale:     1,
+		FixedStep: step,
+		now:       wallSeconds(),
+	}
+	c.last = c.now()
+	c.freeze()
+	return c
+}
+
+// freeze pauses the clock at the time given by the -time flag, if set
+func (c *Clock) freeze() {
+	if settings.timeSet {
+		c.Seek(settings.time)
+		c.Pause()
+	}
+}
+
+// wallSeconds returns a function giving the seconds since it was made. It
+// is used instead of glfw.GetTime so clocks work without glfw.
+func wallSeconds() func() float64 {
+	start := time.Now()
+	return func() float64 {
+		return time.Since(start).Seconds()
+	}
+}
+
+// Tick advances the clock by one frame. It should be called once at the
+// start of every frame.
+func (c *Clock) Tick() {
+	now := c.now()
+	elapsed := now - c.last
+	c.last = now
+	if c.FixedStep > 0 {
+		elapsed = c.FixedStep
+	}
+	if c.paused {
+		c.delta = 0
+		return
+	}
+	c.delta = elapsed * c.Scale
+	c.time += c.delta
+	c.accumulator += c.delta
+	c.frame++
+}
+
+// Time returns the seconds of unpaused, scaled time since the clock started
+func (c *Clock) Time() float64 {
+	return c.time
+}
+
+// Delta returns the seconds the last Tick advanced by, zero while paused
+func (c *Clock) Delta() float64 {
+	return c.delta
+}
+
+// Frame returns the number of frames the clock has advanced
+func (c *Clock) Frame() int {
+	return c.frame
+}
+
+// Pause stops time from passing until Resume
+func (c *Clock) Pause() {
+	c.paused = true
+}
+
+// Resume restarts a paused clock
+func (c *Clock) Resume() {
+	c.paused = false
+}
+
+// TogglePause pauses a running clock or resumes a paused one
+func (c *Clock) TogglePause() {
+	c.paused = !c.paused
+}
+
+// Paused reports whether the clock is paused
+func (c *Clock) Paused() bool {
+	return c.paused
+}
+
+// Seek jumps to time t, e.g. to scrub through an animation while paused
+func (c *Clock) Seek(t float64) {
+	c.time = t
+	c.accumulator = 0
+}
+
+// FixedUpdate calls update with a fixed step for each whole step of time
+// that has passed, carrying the remainder over to the next frame. This
+// keeps simulations independent of the frame rate. A step that is not
+// positive does nothing.
+func (c *Clock) FixedUpdate(step float64, update func(dt float64)) {
+	if step <= 0 {
+		return
+	}
+	for c.accumulator >= step {
+		update(step)
+		c.accumulator -= step
+	}
+}
+
+// SetUniforms sets the standard time uniforms on s, for those it declares:
+//
+//	uniform float uTime;       // Time
+//	uniform float uDeltaTime;  // Delta
+//	uniform int uFrame;        // Frame
+//
+// The program must be in use.
+func (c *Clock) SetUniforms(s *Shader) {
+	if _, ok := s.Uniform("uTime"); ok {
+		s.SetFloat("uTime", float32(c.time))
+	}
+	if _, ok := s.Uniform("uDeltaTime"); ok {
+		s.SetFloat("uDeltaTime", float32(c.delta))
+	}
+	if _, ok := s.Uniform("uFrame"); ok {
+		s.SetInt("uFrame", int32(c.frame))
+	}
+}
@@ -0,0 +1,85 @@
+package render
+
+import "testing"
+
+// fixedClock returns a clock stepping by step seconds a frame, with a wall
+// clock that jumps by a different amount each call
+func fixedClock(step float64) *Clock {
+	var wall float64
+	return &Clock{
+		Scale:     1,
+		FixedStep: step,
+		now: func() float64 {
+			wall += 0.37
+			return wall
+		},
+	}
+}
+
+func TestClockFixedStep(t *testing.T) {
+	c := fixedClock(0.25)
+	for i := 0; i < 10; i++ {
+		c.Tick()
+	}
+	if c.Frame() != 10 || c.Time() != 2.5 || c.Delta() != 0.25 {
+		t.Errorf("frame %d, time %v, delta %v, want 10, 2.5 and 0.25", c.Frame(), c.Time(), c.Delta())
+	}
+
+	c.Scale = 2
+	c.Tick()
+	if c.Time() != 3 || c.Delta() != 0.5 {
+		t.Errorf("scaled: time %v, delta %v, want 3 and 0.5", c.Time(), c.Delta())
+	}
+}
+
+func TestClockPauseAndSeek(t *testing.T) {
+	c := fixedClock(0.5)
+	c.Tick()
+	c.Pause()
+	c.Tick()
+	c.Tick()
+	if c.Frame() != 1 || c.Time() != 0.5 || c.Delta() != 0 {
+		t.Errorf("paused: frame %d, time %v, delta %v, want 1, 0.5 and 0", c.Frame(), c.Time(), c.Delta())
+	}
+	c.Seek(10)
+	if c.Time() != 10 || !c.Paused() {
+		t.Errorf("seek: time %v, paused %v, want 10 and paused", c.Time(), c.Paused())
+	}
+	c.TogglePause()
+	c.Tick()
+	if c.Frame() != 2 || c.Time() != 10.5 || c.Paused() {
+		t.Errorf("resumed: frame %d, time %v, paused %v, want 2, 10.5 and running", c.Frame(), c.Time(), c.Paused())
+	}
+}
+
+func TestClockFixedUpdate(t *testing.T) {
+	c := fixedClock(0.375)
+	updates := 0
+	update := func(dt float64) {
+		if dt != 0.25 {
+			t.Errorf("update with dt %v, want 0.25", dt)
+		}
+		updates++
+	}
+	// 0.375, 0.75 and 1.125 seconds have passed after each tick, which is
+	// 1, 3 and 4 whole steps of 0.25 with the remainders carried over
+	for i, want := range []int{1, 3, 4} {
+		c.Tick()
+		c.FixedUpdate(0.25, update)
+		if updates != want {
+			t.Errorf("tick %d: %d updates, want %d", i+1, updates, want)
+		}
+	}
+
+	// seeking drops the time left over
+	c.Seek(0)
+	c.Tick()
+	c.FixedUpdate(0.25, update)
+	if updates != 5 {
+		t.Errorf("after seek: %d updates, want 5", updates)
+	}
+
+	for _, step := range []float64{0, -1} {
+		c.FixedUpdate(step, func(float64) { t.Fatalf("update called with step %v", step) })
+	}
+}
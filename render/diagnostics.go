@@ -0,0 +1,169 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity is the level of a shader diagnostic
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is a single message from a shader info log, mapped back to the
+// file it came from
+type Diagnostic struct {
+	Severity Severity
+	File     string
+	// Line and Column are 1 based; zero when the driver did not report them
+	Line    int
+	Column  int
+	Message string
+	// Source is the text of the offending line, if known
+	Source string
+}
+
+// String formats the diagnostic compiler-style, e.g.
+// "shader.frag:7: error: undeclared identifier"
+func (d Diagnostic) String() string {
+	pos := d.File
+	if d.Line > 0 {
+		pos += ":" + strconv.Itoa(d.Line)
+		if d.Column > 0 {
+			pos += ":" + strconv.Itoa(d.Column)
+		}
+	}
+	if pos == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", pos, d.Severity, d.Message)
+}
+
+// Format returns the diagnostic followed by an excerpt of the offending line
+func (d Diagnostic) Format() string {
+	if d.Source == "" {
+		return d.String()
+	}
+	gutter := strconv.Itoa(d.Line)
+	excerpt := fmt.Sprintf("%s\n %s | %s", d.String(), gutter, strings.TrimRight(d.Source, "\r"))
+	if d.Column > 0 && d.Column <= len(d.Source)+1 {
+		// keep tabs in the padding so the caret lines up with the source
+		pad := strings.Map(func(r rune) rune {
+			if r == '\t' {
+				return r
+			}
+			return ' '
+		}, d.Source[:d.Column-1])
+		excerpt += fmt.Sprintf("\n %s | %s^", strings.Repeat(" ", len(gutter)), pad)
+	}
+	return excerpt
+}
+
+// info log formats, tried in order:
+//
+//	Mesa:           0:7(12): error: message
+//	NVIDIA:         0(7) : error C0000: message
+//	AMD / 3DLabs:   ERROR: 0:7: message
+//	no position:    ERROR: 1 compilation errors.  No code generated.
+var (
+	mesaLogLine   = regexp.MustCompile(`^(\d+):(\d+)\((\d+)\): (error|warning)\s*: ?(.*)$`)
+	nvidiaLogLine = regexp.MustCompile(`^(\d+)\((\d+)\)\s*: (error|warning)( \w+)?: ?(.*)$`)
+	amdLogLine    = regexp.MustCompile(`^(ERROR|WARNING): (\d+):(\d+): ?(.*)$`)
+	plainLogLine  = regexp.MustCompile(`^(?i)(error|warning): ?(.*)$`)
+)
+
+// ParseInfoLog splits a driver info log into diagnostics. files maps the
+// source string numbers used by the driver to file names; unknown numbers
+// are reported as the number itself. Lines that match none of the known
+// formats are kept as errors without a position. Program logs have no
+// source strings, so files is nil for them.
+func ParseInfoLog(log string, files []string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(log, "\n") {
+		line = strings.TrimSpace(strings.TrimRight(line, "\x00"))
+		if line == "" {
+			continue
+		}
+		diags = append(diags, parseLogLine(line, files))
+	}
+	return diags
+}
+
+func parseLogLine(line string, files []string) Diagnostic {
+	if m := mesaLogLine.FindStringSubmatch(line); m != nil {
+		return Diagnostic{
+			Severity: parseSeverity(m[4]),
+			File:     fileName(m[1], files),
+			Line:     atoi(m[2]),
+			Column:   atoi(m[3]),
+			Message:  m[5],
+		}
+	}
+	if m := nvidiaLogLine.FindStringSubmatch(line); m != nil {
+		msg := m[5]
+		if code := strings.TrimSpace(m[4]); code != "" {
+			msg = code + ": " + msg
+		}
+		return Diagnostic{
+			Severity: parseSeverity(m[3]),
+			File:     fileName(m[1], files),
+			Line:     atoi(m[2]),
+			Message:  msg,
+		}
+	}
+	if m := amdLogLine.FindStringSubmatch(line); m != nil {
+		return Diagnostic{
+			Severity: parseSeverity(m[1]),
+			File:     fileName(m[2], files),
+			Line:     atoi(m[3]),
+			Message:  m[4],
+		}
+	}
+	if m := plainLogLine.FindStringSubmatch(line); m != nil {
+		return Diagnostic{
+			Severity: parseSeverity(m[1]),
+			File:     fileName("0", files),
+			Message:  m[2],
+		}
+	}
+	return Diagnostic{
+		Severity: SeverityError,
+		File:     fileName("0", files),
+		Message:  line,
+	}
+}
+
+func parseSeverity(s string) Severity {
+	if strings.EqualFold(s, "warning") {
+		return SeverityWarning
+	}
+	return SeverityError
+}
+
+func fileName(index string, files []string) string {
+	if files == nil {
+		return ""
+	}
+	i := atoi(index)
+	if i < len(files) {
+		return files[i]
+	}
+	return index
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
@@ -0,0 +1,76 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInfoLog(t *testing.T) {
+	files := []string{"shader.vert", "common.glsl"}
+	tests := []struct {
+		line string
+		want Diagnostic
+	}{
+		// Mesa
+		{
+			"0:7(12): error: `color' undeclared",
+			Diagnostic{Severity: SeverityError, File: "shader.vert", Line: 7, Column: 12, Message: "`color' undeclared"},
+		},
+		{
+			"1:3(5): warning: `x' used uninitialized",
+			Diagnostic{Severity: SeverityWarning, File: "common.glsl", Line: 3, Column: 5, Message: "`x' used uninitialized"},
+		},
+		// NVIDIA
+		{
+			`0(7) : error C1008: undefined variable "color"`,
+			Diagnostic{Severity: SeverityError, File: "shader.vert", Line: 7, Message: `C1008: undefined variable "color"`},
+		},
+		// AMD
+		{
+			"WARNING: 1:4: 'x' : variable is not used",
+			Diagnostic{Severity: SeverityWarning, File: "common.glsl", Line: 4, Message: "'x' : variable is not used"},
+		},
+		// no position, and a source string the driver made up
+		{
+			"ERROR: 1 compilation errors.  No code generated.",
+			Diagnostic{Severity: SeverityError, File: "shader.vert", Message: "1 compilation errors.  No code generated."},
+		},
+		{
+			"5:2(1): error: syntax error",
+			Diagnostic{Severity: SeverityError, File: "5", Line: 2, Column: 1, Message: "syntax error"},
+		},
+		// unknown format
+		{
+			"Compilation failed for an unknown reason",
+			Diagnostic{Severity: SeverityError, File: "shader.vert", Message: "Compilation failed for an unknown reason"},
+		},
+	}
+	for _, test := range tests {
+		diags := ParseInfoLog(test.line+"\n\x00", files)
+		if len(diags) != 1 || !reflect.DeepEqual(diags[0], test.want) {
+			t.Errorf("%q: got %+v, want %+v", test.line, diags, test.want)
+		}
+	}
+}
+
+func TestDiagnosticFormat(t *testing.T) {
+	d := Diagnostic{
+		Severity: SeverityError,
+		File:     "shader.frag",
+		Line:     7,
+		Column:   6,
+		Message:  "undeclared identifier",
+		Source:   "\tx = color;",
+	}
+	want := "shader.frag:7:6: error: undeclared identifier\n 7 | \tx = color;\n   | \t    ^"
+	if got := d.Format(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// a column past the end of the line has no caret
+	d.Column = 40
+	want = "shader.frag:7:40: error: undeclared identifier\n 7 | \tx = color;"
+	if got := d.Format(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompileError is returned when a shader stage fails to compile
+type CompileError struct {
+	// Path is the shader file, or <vertex> or <fragment> for shaders built
+	// from strings
+	Path  string
+	Stage Stage
+	// Log is the driver's info log
+	Log string
+	// Diagnostics is Log parsed and mapped back to the source file
+	Diagnostics []Diagnostic
+}
+
+func (e *CompileError) Error() string {
+	return "failed to compile " + e.Path + formatLog(e.Log, e.Diagnostics)
+}
+
+// LinkError is returned when a program fails to link
+type LinkError struct {
+	Log         string
+	Diagnostics []Diagnostic
+}
+
+func (e *LinkError) Error() string {
+	return "failed to link shader program" + formatLog(e.Log, e.Diagnostics)
+}
+
+// ValidateError is returned by Shader.Validate when the program can not
+// execute in the current gl state
+type ValidateError struct {
+	Log         string
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidateError) Error() string {
+	return "shader program failed validation" + formatLog(e.Log, e.Diagnostics)
+}
+
+// BlockLayoutError is returned when the Go struct of a uniform buffer does
+// not match the layout of a program's uniform block
+type BlockLayoutError struct {
+	Block string
+	// Member is the block member that doesn't match, if any
+	Member  string
+	Message string
+}
+
+func (e *BlockLayoutError) Error() string {
+	if e.Member == "" {
+		return fmt.Sprintf("uniform block %s: %s", e.Block, e.Message)
+	}
+	return fmt.Sprintf("uniform block %s: member %s: %s", e.Block, e.Member, e.Message)
+}
+
+// VertexLayoutError is returned when a vertex layout does not match the
+// vertex inputs of a program
+type VertexLayoutError struct {
+	Attribute string
+	Message   string
+}
+
+func (e *VertexLayoutError) Error() string {
+	return fmt.Sprintf("vertex attribute %s: %s", e.Attribute, e.Message)
+}
+
+// MeshError is returned when vertices do not fit the layout of a mesh
+type MeshError struct {
+	Message string
+}
+
+func (e *MeshError) Error() string {
+	return "mesh: " + e.Message
+}
+
+// ConfigError is returned for a bad line in a config file, such as a
+// bindings file
+type ConfigError struct {
+	Diagnostic
+}
+
+func (e *ConfigError) Error() string {
+	return e.Format()
+}
+
+// FileNotFoundError is returned when a shader or texture file does not exist
+type FileNotFoundError struct {
+	Path string
+}
+
+func (e *FileNotFoundError) Error() string {
+	return fmt.Sprintf("file not found: %s", e.Path)
+}
+
+// UnsupportedImageError is returned when a texture file can not be decoded
+type UnsupportedImageError struct {
+	Path string
+	Err  error
+}
+
+func (e *UnsupportedImageError) Error() string {
+	return fmt.Sprintf("unsupported image %s: %v", e.Path, e.Err)
+}
+
+func (e *UnsupportedImageError) Unwrap() error {
+	return e.Err
+}
+
+// formatLog formats the diagnostics one per line, falling back to the raw
+// log when it could not be parsed
+func formatLog(log string, diags []Diagnostic) string {
+	if len(diags) == 0 {
+		if log == "" {
+			return ""
+		}
+		return ": " + log
+	}
+	msgs := make([]string, len(diags))
+	for i, d := range diags {
+		msgs[i] = d.Format()
+	}
+	return ":\n" + strings.Join(msgs, "\n")
+}
+
+// ModelError is returned for a malformed line in a model file, such as an
+// OBJ or MTL file
+type ModelError struct {
+	Diagnostic
+}
+
+func (e *ModelError) Error() string {
+	return e.Format()
+}
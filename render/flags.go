@@ -0,0 +1,62 @@
+package render
+
+import (
+	"flag"
+	"strconv"
+)
+
+// settings are the command line options of windows, screenshots and
+// clocks. They keep these defaults unless RegisterFlags adds them to a flag
+// set.
+var settings = struct {
+	backend      string
+	frames       int
+	captureFrame int
+	capturePath  string
+	// time is only used if timeSet
+	time    float64
+	timeSet bool
+}{
+	capturePath: "frame-%d.png",
+}
+
+// RegisterFlags adds the flags of the package to fs, usually
+// flag.CommandLine before calling flag.Parse:
+//
+//	-backend        gl context backend, see SelectedBackend
+//	-frames         close the window after this many frames
+//	-capture-frame  save a screenshot of this frame
+//	-capture-path   where to save it
+//	-time           start clocks paused at this time
+//
+// The flags are shared by every flag set they are added to, and adding
+// them keeps the values already set.
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&settings.backend, "backend", settings.backend,
+		`gl context backend, "glfw" or "egl" for headless rendering (default $RENDER_BACKEND, or glfw)`)
+	fs.IntVar(&settings.frames, "frames", settings.frames,
+		"close the window after this many frames; 0 runs until closed, or a single frame when headless")
+	fs.IntVar(&settings.captureFrame, "capture-frame", settings.captureFrame,
+		"save a screenshot of this frame, counting from 1")
+	fs.StringVar(&settings.capturePath, "capture-path", settings.capturePath,
+		"screenshot file, with %d replaced by the frame number; .png or .hdr")
+	fs.Var((*timeValue)(&settings.time), "time",
+		"if set, clocks start paused at this many `seconds`, so frames can be reproduced")
+}
+
+// timeValue is the -time flag, which records that it was set
+type timeValue float64
+
+func (v *timeValue) String() string {
+	return strconv.FormatFloat(float64(*v), 'g', -1, 64)
+}
+
+func (v *timeValue) Set(s string) error {
+	t, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*v = timeValue(t)
+	settings.timeSet = true
+	return nil
+}
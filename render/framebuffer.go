@@ -0,0 +1,48 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// framebuffer is an offscreen render target with a color and a depth and
+// stencil attachment
+type framebuffer struct {
+	id            uint32
+	color         uint32
+	depthStencil  uint32
+	width, height int32
+}
+
+// newFramebuffer creates a framebuffer, binds it for drawing and sets the
+// viewport to cover it
+func newFramebuffer(width int32, height int32) (*framebuffer, error) {
+	fb := &framebuffer{width: width, height: height}
+	gl.GenFramebuffers(1, &fb.id)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.id)
+
+	gl.GenRenderbuffers(1, &fb.color)
+	gl.BindRenderbuffer(gl.RENDERBUFFER, fb.color)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.RGBA8, width, height)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.RENDERBUFFER, fb.color)
+
+	gl.GenRenderbuffers(1, &fb.depthStencil)
+	gl.BindRenderbuffer(gl.RENDERBUFFER, fb.depthStencil)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH24_STENCIL8, width, height)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, gl.RENDERBUFFER, fb.depthStencil)
+
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		fb.delete()
+		return nil, fmt.Errorf("offscreen framebuffer is incomplete: 0x%x", status)
+	}
+	gl.Viewport(0, 0, width, height)
+	return fb, nil
+}
+
+func (fb *framebuffer) delete() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.DeleteRenderbuffers(1, &fb.color)
+	gl.DeleteRenderbuffers(1, &fb.depthStencil)
+	gl.DeleteFramebuffers(1, &fb.id)
+}
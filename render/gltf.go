@@ -0,0 +1,975 @@
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// SkinnedModelLayout is the vertex layout of skinned glTF meshes:
+// ModelLayout followed by the indices of 4 joints and their weights, at
+// locations 3 and 4. Joint indices are converted to float, so the vertex
+// shader declares them as vec4.
+var SkinnedModelLayout = NewVertexLayout(append(append([]VertexAttribute(nil), ModelLayout.Attributes...),
+	VertexAttribute{Name: "aJoints", Location: 3, Count: 4, Type: gl.FLOAT},
+	VertexAttribute{Name: "aWeights", Location: 4, Count: 4, Type: gl.FLOAT},
+)...)
+
+// GLTF is a parsed glTF 2.0 asset, with its buffers read and its accessors
+// unpacked. Meshes, materials, textures, nodes and so on refer to each
+// other by their index in these slices, as in the file; -1 means none.
+type GLTF struct {
+	Meshes     []*GLTFMesh
+	Materials  []*PBRMaterial
+	Textures   []*GLTFTexture
+	Images     []*GLTFImage
+	Nodes      []*GLTFNode
+	Skins      []*Skin
+	Animations []*Animation
+	// Roots are the root nodes of the default scene
+	Roots []int
+}
+
+// GLTFMesh is a glTF mesh, drawn as one or more primitives
+type GLTFMesh struct {
+	Name       string
+	Primitives []*GLTFPrimitive
+}
+
+// GLTFPrimitive is part of a mesh with a single material. Its vertices are
+// laid out as ModelLayout, or SkinnedModelLayout if it has joints and
+// weights. Missing normals and texture coordinates are zero. glTF texture
+// coordinates start at the top of the image, as NewTexture expects.
+type GLTFPrimitive struct {
+	MeshData
+	// Mode is the kind of primitive, e.g. gl.TRIANGLES
+	Mode     uint32
+	Material int
+}
+
+// GLTFNode is a node of the scene hierarchy
+type GLTFNode struct {
+	Name        string
+	Mesh        int
+	Skin        int
+	Children    []int
+	Translation mgl32.Vec3
+	Rotation    mgl32.Quat
+	Scale       mgl32.Vec3
+}
+
+// GLTFImage is an image file, or encoded image data embedded in the asset
+type GLTFImage struct {
+	Name string
+	// Path is the image file, if the image is not embedded
+	Path     string
+	Data     []byte
+	MimeType string
+}
+
+// GLTFTexture is an image with sampler settings. Unset settings are zero,
+// leaving the defaults of NewTexture.
+type GLTFTexture struct {
+	Image     int
+	MagFilter int32
+	MinFilter int32
+	WrapS     int32
+	WrapT     int32
+}
+
+// PBRMaterial is a glTF metallic-roughness material. Textures are indices
+// into GLTF.Textures, -1 if the material has none.
+type PBRMaterial struct {
+	Name                     string
+	BaseColorFactor          mgl32.Vec4
+	BaseColorTexture         int
+	MetallicFactor           float32
+	RoughnessFactor          float32
+	MetallicRoughnessTexture int
+	NormalTexture            int
+	NormalScale              float32
+	OcclusionTexture         int
+	OcclusionStrength        float32
+	EmissiveFactor           mgl32.Vec3
+	EmissiveTexture          int
+	// AlphaMode is OPAQUE, MASK or BLEND
+	AlphaMode   string
+	AlphaCutoff float32
+	DoubleSided bool
+}
+
+// NewPBRMaterial returns a material with the glTF defaults: white, fully
+// metallic and rough, with no textures
+func NewPBRMaterial(name string) *PBRMaterial {
+	return &PBRMaterial{
+		Name:                     name,
+		BaseColorFactor:          mgl32.Vec4{1, 1, 1, 1},
+		BaseColorTexture:         -1,
+		MetallicFactor:           1,
+		RoughnessFactor:          1,
+		MetallicRoughnessTexture: -1,
+		NormalTexture:            -1,
+		NormalScale:              1,
+		OcclusionTexture:         -1,
+		OcclusionStrength:        1,
+		EmissiveTexture:          -1,
+		AlphaMode:                "OPAQUE",
+		AlphaCutoff:              0.5,
+	}
+}
+
+// Skin binds the vertices of a mesh to joint nodes
+type Skin struct {
+	Name   string
+	Joints []int
+	// InverseBindMatrices take vertices from model space into the space of
+	// each joint, one per joint
+	InverseBindMatrices []mgl32.Mat4
+	Skeleton            int
+}
+
+// Animation is a set of channels animating node transforms
+type Animation struct {
+	Name     string
+	Channels []*AnimationChannel
+	// Duration is the time of the last keyframe of any channel
+	Duration float32
+}
+
+// AnimationChannel animates one property of a node
+type AnimationChannel struct {
+	Node int
+	// Path is translation, rotation, scale or weights
+	Path string
+	// Interpolation is LINEAR, STEP or CUBICSPLINE
+	Interpolation string
+	Times         []float32
+	// Values holds Components floats per keyframe, or three times as many
+	// for CUBICSPLINE: an in tangent, the value and an out tangent
+	Values     []float32
+	Components int
+}
+
+// ReadGLTF parses a .gltf or .glb file, reading any external buffers
+func ReadGLTF(path string) (*GLTF, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, &FileNotFoundError{Path: path}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseGLTF(path, data)
+}
+
+// glbMagic starts binary glTF files
+const glbMagic = "glTF"
+
+// ParseGLTF parses a glTF asset held in memory, in JSON or binary (glb)
+// form. name is used in errors, and external buffers and images are
+// relative to its directory. Sparse accessors, morph targets and required
+// extensions are not supported.
+func ParseGLTF(name string, data []byte) (*GLTF, error) {
+	p := &gltfParser{name: name, dir: filepath.Dir(name)}
+	text := data
+	if bytes.HasPrefix(data, []byte(glbMagic)) {
+		var err error
+		if text, p.bin, err = p.splitGLB(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(text, &p.doc); err != nil {
+		diag := Diagnostic{Severity: SeverityError, File: name, Message: err.Error()}
+		if syntax, ok := err.(*json.SyntaxError); ok {
+			diag.Line = 1 + bytes.Count(text[:syntax.Offset], []byte("\n"))
+		}
+		return nil, &ModelError{diag}
+	}
+	return p.parse()
+}
+
+type gltfParser struct {
+	name    string
+	dir     string
+	doc     gltfDocument
+	bin     []byte
+	buffers [][]byte
+}
+
+// splitGLB returns the JSON and binary chunks of a glb file
+func (p *gltfParser) splitGLB(data []byte) ([]byte, []byte, error) {
+	if len(data) < 20 {
+		return nil, nil, p.errorf("truncated glb header")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+		return nil, nil, p.errorf("unsupported glb version %d", version)
+	}
+	if length := binary.LittleEndian.Uint32(data[8:]); int(length) < len(data) {
+		data = data[:length]
+	}
+	var text, bin []byte
+	for rest := data[12:]; len(rest) > 0; {
+		if len(rest) < 8 {
+			return nil, nil, p.errorf("truncated glb chunk")
+		}
+		length := int(binary.LittleEndian.Uint32(rest))
+		kind := string(rest[4:8])
+		if len(rest) < 8+length {
+			return nil, nil, p.errorf("truncated glb %q chunk", strings.TrimRight(kind, "\x00"))
+		}
+		switch kind {
+		case "JSON":
+			text = rest[8 : 8+length]
+		case "BIN\x00":
+			if bin == nil {
+				bin = rest[8 : 8+length]
+			}
+		}
+		rest = rest[8+length:]
+	}
+	if text == nil {
+		return nil, nil, p.errorf("glb has no JSON chunk")
+	}
+	return text, bin, nil
+}
+
+func (p *gltfParser) parse() (*GLTF, error) {
+	doc := &p.doc
+	if !strings.HasPrefix(doc.Asset.Version, "2.") {
+		return nil, p.errorf("unsupported glTF version %q", doc.Asset.Version)
+	}
+	if len(doc.ExtensionsRequired) > 0 {
+		return nil, p.errorf("unsupported required extension %s", doc.ExtensionsRequired[0])
+	}
+	if err := p.readBuffers(); err != nil {
+		return nil, err
+	}
+	g := &GLTF{}
+	steps := []func(*GLTF) error{
+		p.parseImages, p.parseTextures, p.parseMaterials, p.parseMeshes,
+		p.parseNodes, p.parseSkins, p.parseAnimations,
+	}
+	for _, step := range steps {
+		if err := step(g); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func (p *gltfParser) readBuffers() error {
+	for i, b := range p.doc.Buffers {
+		var data []byte
+		var err error
+		switch {
+		case b.URI == "" && i == 0 && p.bin != nil:
+			data = p.bin
+		case b.URI == "":
+			return p.errorf("buffer %d has no uri", i)
+		default:
+			data, err = p.readURI(b.URI)
+			if err != nil {
+				return p.errorf("buffer %d: %v", i, err)
+			}
+		}
+		if b.ByteLength < 0 {
+			return p.errorf("buffer %d has negative byteLength %d", i, b.ByteLength)
+		}
+		if len(data) < b.ByteLength {
+			return p.errorf("buffer %d has %d bytes, expected %d", i, len(data), b.ByteLength)
+		}
+		p.buffers = append(p.buffers, data[:b.ByteLength])
+	}
+	return nil
+}
+
+// readURI decodes a base64 data uri, or reads a file relative to the asset
+func (p *gltfParser) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.Index(uri, ",")
+		if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+			return nil, fmt.Errorf("data uri is not base64")
+		}
+		return base64.StdEncoding.DecodeString(uri[comma+1:])
+	}
+	path, err := p.uriPath(uri)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, &FileNotFoundError{Path: path}
+	}
+	return data, err
+}
+
+func (p *gltfParser) uriPath(uri string) (string, error) {
+	file, err := url.PathUnescape(uri)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(p.dir, filepath.FromSlash(file)), nil
+}
+
+func (p *gltfParser) parseImages(g *GLTF) error {
+	for i, img := range p.doc.Images {
+		image := &GLTFImage{Name: img.Name, MimeType: img.MimeType}
+		switch {
+		case img.BufferView != nil:
+			view, err := p.bufferView(*img.BufferView)
+			if err != nil {
+				return p.errorf("image %d: %v", i, err)
+			}
+			image.Data = view
+		case strings.HasPrefix(img.URI, "data:"):
+			data, err := p.readURI(img.URI)
+			if err != nil {
+				return p.errorf("image %d: %v", i, err)
+			}
+			image.Data = data
+		case img.URI != "":
+			path, err := p.uriPath(img.URI)
+			if err != nil {
+				return p.errorf("image %d: %v", i, err)
+			}
+			image.Path = path
+		default:
+			return p.errorf("image %d has no uri or bufferView", i)
+		}
+		g.Images = append(g.Images, image)
+	}
+	return nil
+}
+
+func (p *gltfParser) parseTextures(g *GLTF) error {
+	for i, t := range p.doc.Textures {
+		texture := &GLTFTexture{Image: -1}
+		if t.Source != nil {
+			if *t.Source < 0 || *t.Source >= len(g.Images) {
+				return p.errorf("texture %d: image %d out of range", i, *t.Source)
+			}
+			texture.Image = *t.Source
+		}
+		if t.Sampler != nil {
+			if *t.Sampler < 0 || *t.Sampler >= len(p.doc.Samplers) {
+				return p.errorf("texture %d: sampler %d out of range", i, *t.Sampler)
+			}
+			s := p.doc.Samplers[*t.Sampler]
+			texture.MagFilter, texture.MinFilter = s.MagFilter, s.MinFilter
+			texture.WrapS, texture.WrapT = s.WrapS, s.WrapT
+		}
+		g.Textures = append(g.Textures, texture)
+	}
+	return nil
+}
+
+func (p *gltfParser) parseMaterials(g *GLTF) error {
+	for i, m := range p.doc.Materials {
+		material := NewPBRMaterial(m.Name)
+		if pbr := m.PBRMetallicRoughness; pbr != nil {
+			if pbr.BaseColorFactor != nil {
+				material.BaseColorFactor = *pbr.BaseColorFactor
+			}
+			if pbr.MetallicFactor != nil {
+				material.MetallicFactor = *pbr.MetallicFactor
+			}
+			if pbr.RoughnessFactor != nil {
+				material.RoughnessFactor = *pbr.RoughnessFactor
+			}
+			material.BaseColorTexture = textureIndex(pbr.BaseColorTexture)
+			material.MetallicRoughnessTexture = textureIndex(pbr.MetallicRoughnessTexture)
+		}
+		material.NormalTexture = textureIndex(m.NormalTexture)
+		if m.NormalTexture != nil && m.NormalTexture.Scale != nil {
+			material.NormalScale = *m.NormalTexture.Scale
+		}
+		material.OcclusionTexture = textureIndex(m.OcclusionTexture)
+		if m.OcclusionTexture != nil && m.OcclusionTexture.Strength != nil {
+			material.OcclusionStrength = *m.OcclusionTexture.Strength
+		}
+		material.EmissiveTexture = textureIndex(m.EmissiveTexture)
+		if m.EmissiveFactor != nil {
+			material.EmissiveFactor = *m.EmissiveFactor
+		}
+		if m.AlphaMode != "" {
+			material.AlphaMode = m.AlphaMode
+		}
+		if m.AlphaCutoff != nil {
+			material.AlphaCutoff = *m.AlphaCutoff
+		}
+		material.DoubleSided = m.DoubleSided
+		infos := []*gltfTextureInfo{m.NormalTexture, m.OcclusionTexture, m.EmissiveTexture}
+		if pbr := m.PBRMetallicRoughness; pbr != nil {
+			infos = append(infos, pbr.BaseColorTexture, pbr.MetallicRoughnessTexture)
+		}
+		for _, info := range infos {
+			if info != nil && (info.Index < 0 || info.Index >= len(g.Textures)) {
+				return p.errorf("material %d: texture %d out of range", i, info.Index)
+			}
+		}
+		g.Materials = append(g.Materials, material)
+	}
+	return nil
+}
+
+func textureIndex(info *gltfTextureInfo) int {
+	if info == nil {
+		return -1
+	}
+	return info.Index
+}
+
+func (p *gltfParser) parseMeshes(g *GLTF) error {
+	for i, m := range p.doc.Meshes {
+		mesh := &GLTFMesh{Name: m.Name}
+		for j, prim := range m.Primitives {
+			primitive, err := p.primitive(prim, len(g.Materials))
+			if err != nil {
+				return p.errorf("mesh %d primitive %d: %v", i, j, err)
+			}
+			mesh.Primitives = append(mesh.Primitives, primitive)
+		}
+		g.Meshes = append(g.Meshes, mesh)
+	}
+	return nil
+}
+
+// primitiveAttributes are the glTF attributes in each layout, in order,
+// with their component counts
+var primitiveAttributes = []struct {
+	name       string
+	components int
+}{
+	{"POSITION", 3}, {"NORMAL", 3}, {"TEXCOORD_0", 2}, {"JOINTS_0", 4}, {"WEIGHTS_0", 4},
+}
+
+func (p *gltfParser) primitive(prim gltfPrimitive, materials int) (*GLTFPrimitive, error) {
+	primitive := &GLTFPrimitive{Mode: gl.TRIANGLES, Material: -1}
+	if prim.Mode != nil {
+		// glTF modes are the gl primitive types
+		if *prim.Mode < 0 || *prim.Mode > gl.TRIANGLE_FAN {
+			return nil, fmt.Errorf("unknown mode %d", *prim.Mode)
+		}
+		primitive.Mode = uint32(*prim.Mode)
+	}
+	if prim.Material != nil {
+		if *prim.Material < 0 || *prim.Material >= materials {
+			return nil, fmt.Errorf("material %d out of range", *prim.Material)
+		}
+		primitive.Material = *prim.Material
+	}
+	if len(prim.Targets) > 0 {
+		return nil, fmt.Errorf("morph targets are not supported")
+	}
+
+	position, ok := prim.Attributes["POSITION"]
+	if !ok {
+		return nil, fmt.Errorf("no POSITION attribute")
+	}
+	// the position accessor is checked before its count sizes the vertices
+	positions, components, err := p.accessor(position)
+	if err != nil {
+		return nil, fmt.Errorf("POSITION: %v", err)
+	}
+	count := len(positions) / components
+	_, joints := prim.Attributes["JOINTS_0"]
+	_, weights := prim.Attributes["WEIGHTS_0"]
+	attributes := primitiveAttributes[:3]
+	primitive.Layout = ModelLayout
+	if joints && weights {
+		attributes = primitiveAttributes
+		primitive.Layout = SkinnedModelLayout
+	}
+
+	stride := 0
+	for _, a := range attributes {
+		stride += a.components
+	}
+	primitive.Vertices = make([]float32, count*stride)
+	offset := 0
+	for _, a := range attributes {
+		if accessor, ok := prim.Attributes[a.name]; ok {
+			values, components, err := p.accessor(accessor)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", a.name, err)
+			}
+			if components != a.components || len(values) != count*components {
+				return nil, fmt.Errorf("%s has %d values of %d components, expected %d of %d",
+					a.name, len(values)/components, components, count, a.components)
+			}
+			for v := 0; v < count; v++ {
+				copy(primitive.Vertices[v*stride+offset:], values[v*components:(v+1)*components])
+			}
+		}
+		offset += a.components
+	}
+
+	if prim.Indices != nil {
+		values, components, err := p.accessor(*prim.Indices)
+		if err != nil {
+			return nil, fmt.Errorf("indices: %v", err)
+		}
+		if components != 1 {
+			return nil, fmt.Errorf("indices are not scalars")
+		}
+		primitive.Indices = make([]uint32, len(values))
+		for i, v := range values {
+			if v < 0 || int(v) >= count {
+				return nil, fmt.Errorf("index %v out of range, %d vertices", v, count)
+			}
+			primitive.Indices[i] = uint32(v)
+		}
+	}
+	return primitive, nil
+}
+
+func (p *gltfParser) parseNodes(g *GLTF) error {
+	parents := make([]int, len(p.doc.Nodes))
+	for i := range parents {
+		parents[i] = -1
+	}
+	for i, n := range p.doc.Nodes {
+		node := &GLTFNode{
+			Name:     n.Name,
+			Mesh:     -1,
+			Skin:     -1,
+			Children: n.Children,
+			Rotation: mgl32.QuatIdent(),
+			Scale:    mgl32.Vec3{1, 1, 1},
+		}
+		if n.Mesh != nil {
+			if *n.Mesh < 0 || *n.Mesh >= len(g.Meshes) {
+				return p.errorf("node %d: mesh %d out of range", i, *n.Mesh)
+			}
+			node.Mesh = *n.Mesh
+		}
+		if n.Skin != nil {
+			if *n.Skin < 0 || *n.Skin >= len(p.doc.Skins) {
+				return p.errorf("node %d: skin %d out of range", i, *n.Skin)
+			}
+			node.Skin = *n.Skin
+		}
+		if n.Matrix != nil {
+			node.Translation, node.Rotation, node.Scale = decompose(*n.Matrix)
+		}
+		if n.Translation != nil {
+			node.Translation = *n.Translation
+		}
+		if n.Rotation != nil {
+			r := *n.Rotation
+			node.Rotation = mgl32.Quat{W: r[3], V: mgl32.Vec3{r[0], r[1], r[2]}}
+		}
+		if n.Scale != nil {
+			node.Scale = *n.Scale
+		}
+		for _, child := range n.Children {
+			if child < 0 || child >= len(p.doc.Nodes) {
+				return p.errorf("node %d: child %d out of range", i, child)
+			}
+			if parents[child] >= 0 {
+				return p.errorf("node %d is a child of both node %d and node %d", child, parents[child], i)
+			}
+			parents[child] = i
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+	// with at most one parent each, a cycle is a chain of parents that
+	// never reaches a root
+	for i := range parents {
+		n := i
+		for steps := 0; parents[n] >= 0; steps++ {
+			if steps > len(parents) {
+				return p.errorf("node %d is its own ancestor", i)
+			}
+			n = parents[n]
+		}
+	}
+
+	switch {
+	case p.doc.Scene != nil || len(p.doc.Scenes) > 0:
+		scene := 0
+		if p.doc.Scene != nil {
+			scene = *p.doc.Scene
+		}
+		if scene < 0 || scene >= len(p.doc.Scenes) {
+			return p.errorf("scene %d out of range", scene)
+		}
+		for _, root := range p.doc.Scenes[scene].Nodes {
+			if root < 0 || root >= len(g.Nodes) {
+				return p.errorf("scene %d: node %d out of range", scene, root)
+			}
+			g.Roots = append(g.Roots, root)
+		}
+	default:
+		for i, parent := range parents {
+			if parent < 0 {
+				g.Roots = append(g.Roots, i)
+			}
+		}
+	}
+	return nil
+}
+
+// decompose splits a transform matrix without shear into translation,
+// rotation and scale
+func decompose(m mgl32.Mat4) (mgl32.Vec3, mgl32.Quat, mgl32.Vec3) {
+	scale := mgl32.Vec3{m.Col(0).Vec3().Len(), m.Col(1).Vec3().Len(), m.Col(2).Vec3().Len()}
+	if m.Mat3().Det() < 0 {
+		scale[0] = -scale[0]
+	}
+	var rotation mgl32.Mat4
+	for c := 0; c < 3; c++ {
+		if scale[c] != 0 {
+			rotation.SetCol(c, m.Col(c).Mul(1/scale[c]))
+		}
+	}
+	rotation.Set(3, 3, 1)
+	return m.Col(3).Vec3(), mgl32.Mat4ToQuat(rotation).Normalize(), scale
+}
+
+func (p *gltfParser) parseSkins(g *GLTF) error {
+	for i, s := range p.doc.Skins {
+		skin := &Skin{Name: s.Name, Joints: s.Joints, Skeleton: -1}
+		for _, joint := range s.Joints {
+			if joint < 0 || joint >= len(g.Nodes) {
+				return p.errorf("skin %d: joint %d out of range", i, joint)
+			}
+		}
+		if s.Skeleton != nil {
+			if *s.Skeleton < 0 || *s.Skeleton >= len(g.Nodes) {
+				return p.errorf("skin %d: skeleton %d out of range", i, *s.Skeleton)
+			}
+			skin.Skeleton = *s.Skeleton
+		}
+		if s.InverseBindMatrices != nil {
+			values, components, err := p.accessor(*s.InverseBindMatrices)
+			if err != nil {
+				return p.errorf("skin %d: inverseBindMatrices: %v", i, err)
+			}
+			if components != 16 || len(values) != 16*len(s.Joints) {
+				return p.errorf("skin %d: expected %d inverse bind matrices", i, len(s.Joints))
+			}
+			for j := range s.Joints {
+				var m mgl32.Mat4
+				copy(m[:], values[16*j:])
+				skin.InverseBindMatrices = append(skin.InverseBindMatrices, m)
+			}
+		} else {
+			for range s.Joints {
+				skin.InverseBindMatrices = append(skin.InverseBindMatrices, mgl32.Ident4())
+			}
+		}
+		g.Skins = append(g.Skins, skin)
+	}
+	return nil
+}
+
+func (p *gltfParser) parseAnimations(g *GLTF) error {
+	for i, a := range p.doc.Animations {
+		animation := &Animation{Name: a.Name}
+		for j, c := range a.Channels {
+			if c.Target.Node == nil {
+				// targets without a node are for extensions
+				continue
+			}
+			if *c.Target.Node < 0 || *c.Target.Node >= len(g.Nodes) {
+				return p.errorf("animation %d channel %d: node %d out of range", i, j, *c.Target.Node)
+			}
+			if c.Sampler < 0 || c.Sampler >= len(a.Samplers) {
+				return p.errorf("animation %d channel %d: sampler %d out of range", i, j, c.Sampler)
+			}
+			channel, err := p.channel(a.Samplers[c.Sampler], c.Target.Path)
+			if err != nil {
+				return p.errorf("animation %d channel %d: %v", i, j, err)
+			}
+			channel.Node = *c.Target.Node
+			if n := len(channel.Times); n > 0 && channel.Times[n-1] > animation.Duration {
+				animation.Duration = channel.Times[n-1]
+			}
+			animation.Channels = append(animation.Channels, channel)
+		}
+		g.Animations = append(g.Animations, animation)
+	}
+	return nil
+}
+
+func (p *gltfParser) channel(sampler gltfAnimationSampler, path string) (*AnimationChannel, error) {
+	channel := &AnimationChannel{Path: path, Interpolation: sampler.Interpolation}
+	if channel.Interpolation == "" {
+		channel.Interpolation = "LINEAR"
+	}
+	switch channel.Interpolation {
+	case "LINEAR", "STEP", "CUBICSPLINE":
+	default:
+		return nil, fmt.Errorf("unknown interpolation %s", channel.Interpolation)
+	}
+	times, components, err := p.accessor(sampler.Input)
+	if err != nil {
+		return nil, fmt.Errorf("input: %v", err)
+	}
+	if components != 1 {
+		return nil, fmt.Errorf("input times are not scalars")
+	}
+	values, components, err := p.accessor(sampler.Output)
+	if err != nil {
+		return nil, fmt.Errorf("output: %v", err)
+	}
+	channel.Times, channel.Values, channel.Components = times, values, components
+	if path == "weights" {
+		// one value per morph target, which are not supported
+		return channel, nil
+	}
+	want := map[string]int{"translation": 3, "rotation": 4, "scale": 3}[path]
+	if want == 0 {
+		return nil, fmt.Errorf("unknown target path %q", path)
+	}
+	keys := len(times)
+	if channel.Interpolation == "CUBICSPLINE" {
+		keys *= 3
+	}
+	if components != want || len(values) != keys*components {
+		return nil, fmt.Errorf("%s expects %d values of %d components, got %d of %d",
+			path, keys, want, len(values)/components, components)
+	}
+	return channel, nil
+}
+
+// accessorComponents are the number of components of each accessor type
+var accessorComponents = map[string]int{
+	"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT2": 4, "MAT3": 9, "MAT4": 16,
+}
+
+// accessor unpacks an accessor into floats, returning them and the number
+// of components of each element. Normalized integers are mapped to 0..1
+// or -1..1.
+func (p *gltfParser) accessor(index int) ([]float32, int, error) {
+	if index < 0 || index >= len(p.doc.Accessors) {
+		return nil, 0, fmt.Errorf("accessor %d out of range", index)
+	}
+	a := p.doc.Accessors[index]
+	if a.Sparse != nil {
+		return nil, 0, fmt.Errorf("accessor %d is sparse, which is not supported", index)
+	}
+	components, ok := accessorComponents[a.Type]
+	switch a.ComponentType {
+	case gl.BYTE, gl.UNSIGNED_BYTE, gl.SHORT, gl.UNSIGNED_SHORT, gl.UNSIGNED_INT, gl.FLOAT:
+	default:
+		ok = false
+	}
+	if !ok {
+		return nil, 0, fmt.Errorf("accessor %d has unknown type %s of %d", index, a.Type, a.ComponentType)
+	}
+	if a.Count < 0 {
+		return nil, 0, fmt.Errorf("accessor %d has negative count %d", index, a.Count)
+	}
+	if a.ByteOffset < 0 {
+		return nil, 0, fmt.Errorf("accessor %d has negative byteOffset %d", index, a.ByteOffset)
+	}
+	size := componentSizes[a.ComponentType]
+	elementSize := components * int(size)
+	if a.BufferView == nil {
+		// no data means all zeros
+		return make([]float32, a.Count*components), components, nil
+	}
+	view, err := p.bufferView(*a.BufferView)
+	if err != nil {
+		return nil, 0, fmt.Errorf("accessor %d: %v", index, err)
+	}
+	stride := p.doc.BufferViews[*a.BufferView].ByteStride
+	if stride < 0 || (stride != 0 && stride < elementSize) {
+		return nil, 0, fmt.Errorf("accessor %d: byteStride %d of buffer view %d is less than its %d byte elements",
+			index, stride, *a.BufferView, elementSize)
+	}
+	if stride == 0 {
+		stride = elementSize
+	}
+	// compared by subtraction, as huge counts and strides overflow a sum
+	if last := len(view) - a.ByteOffset - elementSize; a.Count > 0 && (last < 0 || a.Count-1 > last/stride) {
+		return nil, 0, fmt.Errorf("accessor %d runs past the end of buffer view %d", index, *a.BufferView)
+	}
+	values := make([]float32, a.Count*components)
+	for e := 0; e < a.Count; e++ {
+		element := view[a.ByteOffset+e*stride:]
+		for c := 0; c < components; c++ {
+			values[e*components+c] = readComponent(element[c*int(size):], a.ComponentType, a.Normalized)
+		}
+	}
+	return values, components, nil
+}
+
+func readComponent(b []byte, componentType uint32, normalized bool) float32 {
+	var v, max float64
+	switch componentType {
+	case gl.BYTE:
+		v, max = float64(int8(b[0])), 127
+	case gl.UNSIGNED_BYTE:
+		v, max = float64(b[0]), 255
+	case gl.SHORT:
+		v, max = float64(int16(binary.LittleEndian.Uint16(b))), 32767
+	case gl.UNSIGNED_SHORT:
+		v, max = float64(binary.LittleEndian.Uint16(b)), 65535
+	case gl.UNSIGNED_INT:
+		v, max = float64(binary.LittleEndian.Uint32(b)), 4294967295
+	case gl.FLOAT:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	}
+	if normalized {
+		return float32(math.Max(v/max, -1))
+	}
+	return float32(v)
+}
+
+// bufferView returns the bytes of a buffer view
+func (p *gltfParser) bufferView(index int) ([]byte, error) {
+	if index < 0 || index >= len(p.doc.BufferViews) {
+		return nil, fmt.Errorf("buffer view %d out of range", index)
+	}
+	v := p.doc.BufferViews[index]
+	if v.Buffer < 0 || v.Buffer >= len(p.buffers) {
+		return nil, fmt.Errorf("buffer view %d: buffer %d out of range", index, v.Buffer)
+	}
+	buffer := p.buffers[v.Buffer]
+	if v.ByteOffset < 0 || v.ByteLength < 0 || v.ByteOffset > len(buffer) || v.ByteLength > len(buffer)-v.ByteOffset {
+		return nil, fmt.Errorf("buffer view %d runs past the end of buffer %d", index, v.Buffer)
+	}
+	return buffer[v.ByteOffset : v.ByteOffset+v.ByteLength], nil
+}
+
+func (p *gltfParser) errorf(format string, args ...interface{}) error {
+	return modelErrorf(Diagnostic{Severity: SeverityError, File: p.name}, format, args...)
+}
+
+// gltfDocument is the JSON of a glTF asset. Field names match the JSON
+// keys case-insensitively, so only optional values with non-zero defaults
+// need pointers.
+type gltfDocument struct {
+	Asset struct {
+		Version string
+	}
+	ExtensionsRequired []string
+	Scene              *int
+	Scenes             []struct {
+		Nodes []int
+	}
+	Nodes  []gltfNode
+	Meshes []struct {
+		Name       string
+		Primitives []gltfPrimitive
+	}
+	Accessors   []gltfAccessor
+	BufferViews []struct {
+		Buffer     int
+		ByteOffset int
+		ByteLength int
+		ByteStride int
+	}
+	Buffers []struct {
+		URI        string
+		ByteLength int
+	}
+	Materials []gltfMaterial
+	Textures  []struct {
+		Sampler *int
+		Source  *int
+	}
+	Images []struct {
+		Name       string
+		URI        string
+		MimeType   string
+		BufferView *int
+	}
+	Samplers []struct {
+		MagFilter int32
+		MinFilter int32
+		WrapS     int32
+		WrapT     int32
+	}
+	Skins []struct {
+		Name                string
+		InverseBindMatrices *int
+		Skeleton            *int
+		Joints              []int
+	}
+	Animations []struct {
+		Name     string
+		Channels []struct {
+			Sampler int
+			Target  struct {
+				Node *int
+				Path string
+			}
+		}
+		Samplers []gltfAnimationSampler
+	}
+}
+
+type gltfNode struct {
+	Name        string
+	Mesh        *int
+	Skin        *int
+	Children    []int
+	Matrix      *mgl32.Mat4
+	Translation *mgl32.Vec3
+	// Rotation is x, y, z, w
+	Rotation *mgl32.Vec4
+	Scale    *mgl32.Vec3
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int
+	Indices    *int
+	Material   *int
+	Mode       *int
+	Targets    []map[string]int
+}
+
+type gltfAccessor struct {
+	BufferView    *int
+	ByteOffset    int
+	ComponentType uint32
+	Normalized    bool
+	Count         int
+	Type          string
+	Sparse        *json.RawMessage
+}
+
+type gltfMaterial struct {
+	Name                 string
+	PBRMetallicRoughness *struct {
+		BaseColorFactor          *mgl32.Vec4
+		BaseColorTexture         *gltfTextureInfo
+		MetallicFactor           *float32
+		RoughnessFactor          *float32
+		MetallicRoughnessTexture *gltfTextureInfo
+	}
+	NormalTexture    *gltfTextureInfo
+	OcclusionTexture *gltfTextureInfo
+	EmissiveTexture  *gltfTextureInfo
+	EmissiveFactor   *mgl32.Vec3
+	AlphaMode        string
+	AlphaCutoff      *float32
+	DoubleSided      bool
+}
+
+type gltfTextureInfo struct {
+	Index    int
+	Scale    *float32
+	Strength *float32
+}
+
+type gltfAnimationSampler struct {
+	Input         int
+	Output        int
+	Interpolation string
+}
@@ -0,0 +1,359 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Scene is a glTF asset uploaded to gl, with a Transform for every node
+type Scene struct {
+	Asset *GLTF
+	Nodes []*SceneNode
+	// Roots are the root nodes of the default scene
+	Roots []*SceneNode
+	// Meshes holds a Mesh for each primitive of each glTF mesh
+	Meshes [][]*Mesh
+	// Textures holds a Texture for each glTF texture, nil if it has no
+	// image. Textures of the same image and sampler are the same Texture.
+	Textures []*Texture
+
+	// white is bound in place of missing material textures
+	white *Texture
+}
+
+// SceneNode is a node of a scene. Its Transform is parented to the
+// Transform of its parent node, and is what animations change.
+type SceneNode struct {
+	Name string
+	*Transform
+	// Mesh and Skin are indices into the asset, -1 if the node has none
+	Mesh     int
+	Skin     int
+	Children []*SceneNode
+}
+
+// LoadGLTF reads a .gltf or .glb file and uploads its meshes and textures
+func LoadGLTF(path string) (*Scene, error) {
+	asset, err := ReadGLTF(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewScene(path, asset)
+}
+
+// NewScene uploads a parsed glTF asset. name is used in errors.
+func NewScene(name string, asset *GLTF) (*Scene, error) {
+	sc := &Scene{Asset: asset}
+	loaded := map[GLTFTexture]*Texture{}
+	for i, t := range asset.Textures {
+		tx, err := sc.loadTexture(name, asset, t, loaded)
+		if err != nil {
+			sc.Delete()
+			return nil, fmt.Errorf("texture %d: %v", i, err)
+		}
+		sc.Textures = append(sc.Textures, tx)
+	}
+	sc.white = newWhiteTexture()
+
+	for i, m := range asset.Meshes {
+		sc.Meshes = append(sc.Meshes, nil)
+		for j, primitive := range m.Primitives {
+			mesh, err := primitive.Upload()
+			if err != nil {
+				sc.Delete()
+				return nil, fmt.Errorf("mesh %d primitive %d: %v", i, j, err)
+			}
+			mesh.Mode = primitive.Mode
+			sc.Meshes[i] = append(sc.Meshes[i], mesh)
+		}
+	}
+
+	sc.buildNodes()
+	return sc, nil
+}
+
+// buildNodes creates the nodes of the asset and parents their transforms
+func (sc *Scene) buildNodes() {
+	for _, n := range sc.Asset.Nodes {
+		node := &SceneNode{Name: n.Name, Transform: NewTransform(), Mesh: n.Mesh, Skin: n.Skin}
+		node.Position, node.Rotation, node.Scale = n.Translation, n.Rotation, n.Scale
+		sc.Nodes = append(sc.Nodes, node)
+	}
+	for i, n := range sc.Asset.Nodes {
+		for _, child := range n.Children {
+			sc.Nodes[i].Children = append(sc.Nodes[i].Children, sc.Nodes[child])
+			sc.Nodes[i].AddChild(sc.Nodes[child].Transform)
+		}
+	}
+	for _, root := range sc.Asset.Roots {
+		sc.Roots = append(sc.Roots, sc.Nodes[root])
+	}
+}
+
+// Model returns the model matrix the mesh of the node is drawn with: its
+// World, or the identity if it is skinned, as its joint matrices already
+// place it in the world and glTF ignores the transform of skinned nodes
+func (n *SceneNode) Model() mgl32.Mat4 {
+	if n.Skin >= 0 {
+		return mgl32.Ident4()
+	}
+	return n.World()
+}
+
+// loadTexture creates a texture from an image file through NewTexture, or
+// from embedded image data, and applies its sampler settings. Textures of
+// the same image and sampler settings share one, through loaded. A texture
+// with no image is nil, and drawn as a missing one.
+func (sc *Scene) loadTexture(name string, asset *GLTF, t *GLTFTexture, loaded map[GLTFTexture]*Texture) (*Texture, error) {
+	if t.Image < 0 {
+		return nil, nil
+	}
+	if tx, ok := loaded[*t]; ok {
+		return tx, nil
+	}
+	img := asset.Images[t.Image]
+	var tx *Texture
+	if img.Path != "" {
+		var err error
+		if tx, err = NewTexture(img.Path); err != nil {
+			return nil, err
+		}
+	} else {
+		decoded, _, err := image.Decode(bytes.NewReader(img.Data))
+		if err != nil {
+			return nil, &UnsupportedImageError{Path: fmt.Sprintf("%s image %d", name, t.Image), Err: err}
+		}
+		tx = NewTextureFromImage(decoded)
+	}
+	loaded[*t] = tx
+	params := []struct {
+		name  uint32
+		value int32
+	}{
+		{gl.TEXTURE_MAG_FILTER, t.MagFilter},
+		{gl.TEXTURE_MIN_FILTER, t.MinFilter},
+		{gl.TEXTURE_WRAP_S, t.WrapS},
+		{gl.TEXTURE_WRAP_T, t.WrapT},
+	}
+	for _, p := range params {
+		if p.value != 0 {
+			gl.TexParameteri(gl.TEXTURE_2D, p.name, p.value)
+		}
+	}
+	return tx, nil
+}
+
+// Draw draws every node with a mesh in the default scene. It sets these
+// uniforms on s, for those it declares:
+//
+//	uniform mat4 model;                          // the node's Model
+//	uniform mat4 uJoints[N];                     // JointMatrices, if skinned
+//	uniform vec4 uBaseColorFactor;
+//	uniform float uMetallicFactor;
+//	uniform float uRoughnessFactor;
+//	uniform vec3 uEmissiveFactor;
+//	uniform sampler2D uBaseColorTexture;         // unit 0
+//	uniform sampler2D uMetallicRoughnessTexture; // unit 1
+//	uniform sampler2D uNormalTexture;            // unit 2
+//	uniform sampler2D uOcclusionTexture;         // unit 3
+//	uniform sampler2D uEmissiveTexture;          // unit 4
+//
+// Missing textures, and textures with no image, are bound as 1x1 white,
+// except the normal texture which is left unbound. The program must be in
+// use.
+func (sc *Scene) Draw(s *Shader) {
+	for _, root := range sc.Roots {
+		sc.drawNode(s, root)
+	}
+}
+
+func (sc *Scene) drawNode(s *Shader, node *SceneNode) {
+	if node.Mesh >= 0 {
+		if declared(s, "model") {
+			s.SetMat4("model", node.Model())
+		}
+		if node.Skin >= 0 && declared(s, "uJoints") {
+			s.SetMat4Array("uJoints", sc.JointMatrices(node.Skin))
+		}
+		for i, primitive := range sc.Asset.Meshes[node.Mesh].Primitives {
+			sc.bindMaterial(s, primitive.Material)
+			sc.Meshes[node.Mesh][i].Draw()
+		}
+	}
+	for _, child := range node.Children {
+		sc.drawNode(s, child)
+	}
+}
+
+func (sc *Scene) bindMaterial(s *Shader, index int) {
+	material := NewPBRMaterial("")
+	if index >= 0 {
+		material = sc.Asset.Materials[index]
+	}
+	if declared(s, "uBaseColorFactor") {
+		s.SetVec4("uBaseColorFactor", material.BaseColorFactor)
+	}
+	if declared(s, "uMetallicFactor") {
+		s.SetFloat("uMetallicFactor", material.MetallicFactor)
+	}
+	if declared(s, "uRoughnessFactor") {
+		s.SetFloat("uRoughnessFactor", material.RoughnessFactor)
+	}
+	if declared(s, "uEmissiveFactor") {
+		s.SetVec3("uEmissiveFactor", material.EmissiveFactor)
+	}
+	textures := []struct {
+		uniform string
+		index   int
+	}{
+		{"uBaseColorTexture", material.BaseColorTexture},
+		{"uMetallicRoughnessTexture", material.MetallicRoughnessTexture},
+		{"uNormalTexture", material.NormalTexture},
+		{"uOcclusionTexture", material.OcclusionTexture},
+		{"uEmissiveTexture", material.EmissiveTexture},
+	}
+	for unit, t := range textures {
+		if !declared(s, t.uniform) {
+			continue
+		}
+		s.SetSampler(t.uniform, int32(unit))
+		tx := sc.white
+		if t.index >= 0 && sc.Textures[t.index] != nil {
+			tx = sc.Textures[t.index]
+		} else if t.uniform == "uNormalTexture" {
+			continue
+		}
+		tx.Bind(gl.TEXTURE0 + uint32(unit))
+	}
+}
+
+func declared(s *Shader, name string) bool {
+	_, ok := s.Uniform(name)
+	return ok
+}
+
+// JointMatrices returns the matrix of each joint of a skin, taking a
+// vertex from its bind pose to the joint's current pose
+func (sc *Scene) JointMatrices(skin int) []mgl32.Mat4 {
+	s := sc.Asset.Skins[skin]
+	matrices := make([]mgl32.Mat4, len(s.Joints))
+	for i, joint := range s.Joints {
+		matrices[i] = sc.Nodes[joint].World().Mul4(s.InverseBindMatrices[i])
+	}
+	return matrices
+}
+
+// Animate poses the nodes of the scene as animation a is at time t, in
+// seconds. t is clamped to the animation; use math.Mod with Duration to
+// loop it.
+func (sc *Scene) Animate(a *Animation, t float32) {
+	for _, c := range a.Channels {
+		v := c.Sample(t)
+		node := sc.Nodes[c.Node]
+		switch c.Path {
+		case "translation":
+			node.Position = mgl32.Vec3{v[0], v[1], v[2]}
+		case "rotation":
+			node.Rotation = mgl32.Quat{W: v[3], V: mgl32.Vec3{v[0], v[1], v[2]}}
+		case "scale":
+			node.Scale = mgl32.Vec3{v[0], v[1], v[2]}
+		}
+	}
+}
+
+// Sample returns the value of the channel at time t, clamped to its first
+// and last keyframes. Rotations are quaternions x, y, z, w.
+func (c *AnimationChannel) Sample(t float32) []float32 {
+	n := len(c.Times)
+	if n == 0 {
+		return make([]float32, c.Components)
+	}
+	if t <= c.Times[0] {
+		return append([]float32(nil), c.value(0)...)
+	}
+	if t >= c.Times[n-1] {
+		return append([]float32(nil), c.value(n-1)...)
+	}
+	// find the keyframes either side of t
+	k := 0
+	for k+1 < n && c.Times[k+1] <= t {
+		k++
+	}
+	dt := c.Times[k+1] - c.Times[k]
+	s := (t - c.Times[k]) / dt
+	a, b := c.value(k), c.value(k+1)
+	v := make([]float32, c.Components)
+	switch c.Interpolation {
+	case "STEP":
+		copy(v, a)
+	case "CUBICSPLINE":
+		// Hermite spline between the values, with the out tangent of a and
+		// the in tangent of b scaled by the keyframe interval
+		out, in := c.Values[(3*k+2)*c.Components:], c.Values[3*(k+1)*c.Components:]
+		s2, s3 := s*s, s*s*s
+		for i := range v {
+			v[i] = (2*s3-3*s2+1)*a[i] + (s3-2*s2+s)*dt*out[i] + (-2*s3+3*s2)*b[i] + (s3-s2)*dt*in[i]
+		}
+		if c.Path == "rotation" {
+			normalize(v)
+		}
+	default:
+		if c.Path == "rotation" {
+			q := mgl32.QuatSlerp(
+				mgl32.Quat{W: a[3], V: mgl32.Vec3{a[0], a[1], a[2]}},
+				mgl32.Quat{W: b[3], V: mgl32.Vec3{b[0], b[1], b[2]}}, s)
+			return []float32{q.V[0], q.V[1], q.V[2], q.W}
+		}
+		for i := range v {
+			v[i] = a[i] + (b[i]-a[i])*s
+		}
+	}
+	return v
+}
+
+// value returns the value of keyframe k
+func (c *AnimationChannel) value(k int) []float32 {
+	if c.Interpolation == "CUBICSPLINE" {
+		// skip the in tangent
+		k = 3*k + 1
+	}
+	return c.Values[k*c.Components : (k+1)*c.Components]
+}
+
+func normalize(v []float32) {
+	var length float32
+	for _, x := range v {
+		length += x * x
+	}
+	if length == 0 {
+		return
+	}
+	length = float32(1 / math.Sqrt(float64(length)))
+	for i := range v {
+		v[i] *= length
+	}
+}
+
+// Delete frees the meshes and textures of the scene
+func (sc *Scene) Delete() {
+	for _, meshes := range sc.Meshes {
+		for _, mesh := range meshes {
+			mesh.Delete()
+		}
+	}
+	deleted := map[*Texture]bool{}
+	for _, tx := range sc.Textures {
+		if tx != nil && !deleted[tx] {
+			deleted[tx] = true
+			tx.Delete()
+		}
+	}
+	if sc.white != nil {
+		sc.white.Delete()
+	}
+}
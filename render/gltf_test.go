@@ -0,0 +1,313 @@
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// triangleBuffer holds 3 positions, 3 texture coordinates, 3 ushort
+// indices padded to 4 bytes, 2 keyframe times and 2 translations
+func triangleBuffer() []byte {
+	var b bytes.Buffer
+	for _, v := range [][]interface{}{
+		{float32(0), float32(0), float32(0), float32(1), float32(0), float32(0), float32(0), float32(1), float32(0)},
+		{float32(0), float32(0), float32(1), float32(0), float32(0), float32(1)},
+		{uint16(0), uint16(1), uint16(2), uint16(0)},
+		{float32(0), float32(2)},
+		{float32(0), float32(0), float32(0), float32(4), float32(2), float32(0)},
+	} {
+		for _, x := range v {
+			binary.Write(&b, binary.LittleEndian, x)
+		}
+	}
+	return b.Bytes()
+}
+
+// triangleJSON is a glTF asset drawing triangleBuffer, with the buffer uri
+// left as a %s verb
+const triangleJSON = `{
+	"asset": {"version": "2.0"},
+	"scene": 0,
+	"scenes": [{"nodes": [0]}],
+	"nodes": [
+		{"name": "root", "children": [1], "translation": [1, 2, 3]},
+		{"name": "tri", "mesh": 0, "matrix": [2,0,0,0, 0,2,0,0, 0,0,2,0, 5,6,7,1]}
+	],
+	"meshes": [{"name": "triangle", "primitives": [{
+		"attributes": {"POSITION": 0, "TEXCOORD_0": 1},
+		"indices": 2,
+		"material": 0
+	}]}],
+	"materials": [{
+		"name": "red",
+		"pbrMetallicRoughness": {"baseColorFactor": [1, 0, 0, 1], "metallicFactor": 0, "baseColorTexture": {"index": 0}},
+		"emissiveFactor": [0, 0, 1],
+		"alphaMode": "MASK"
+	}],
+	"textures": [{"source": 0, "sampler": 0}],
+	"samplers": [{"magFilter": 9728, "wrapS": 33071}],
+	"images": [{"uri": "textures/red%%20brick.png"}],
+	"animations": [{"name": "move", "channels": [{"sampler": 0, "target": {"node": 0, "path": "translation"}}],
+		"samplers": [{"input": 4, "output": 5}]}],
+	"accessors": [
+		{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+		{"bufferView": 1, "componentType": 5126, "count": 3, "type": "VEC2"},
+		{"bufferView": 2, "componentType": 5123, "count": 3, "type": "SCALAR"},
+		{"componentType": 5126, "count": 1, "type": "VEC3"},
+		{"bufferView": 3, "componentType": 5126, "count": 2, "type": "SCALAR"},
+		{"bufferView": 4, "componentType": 5126, "count": 2, "type": "VEC3"}
+	],
+	"bufferViews": [
+		{"buffer": 0, "byteOffset": 0, "byteLength": 36},
+		{"buffer": 0, "byteOffset": 36, "byteLength": 24},
+		{"buffer": 0, "byteOffset": 60, "byteLength": 8},
+		{"buffer": 0, "byteOffset": 68, "byteLength": 8},
+		{"buffer": 0, "byteOffset": 76, "byteLength": 24}
+	],
+	"buffers": [{%s"byteLength": 100}]
+}`
+
+func dataURI(data []byte) string {
+	return "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+func checkTriangle(t *testing.T, g *GLTF) {
+	t.Helper()
+	if len(g.Meshes) != 1 || len(g.Meshes[0].Primitives) != 1 {
+		t.Fatalf("got %d meshes, want 1 with 1 primitive", len(g.Meshes))
+	}
+	p := g.Meshes[0].Primitives[0]
+	if p.Layout != ModelLayout || p.Mode != gl.TRIANGLES || p.Material != 0 {
+		t.Errorf("primitive = %+v", p)
+	}
+	// position, zero normal, texture coordinates
+	want := []float32{1, 0, 0, 0, 0, 0, 1, 0}
+	if got := p.Vertices[8:16]; !reflect.DeepEqual(got, want) {
+		t.Errorf("vertex 1 = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(p.Indices, []uint32{0, 1, 2}) {
+		t.Errorf("indices = %v", p.Indices)
+	}
+
+	m := g.Materials[0]
+	if m.BaseColorFactor != (mgl32.Vec4{1, 0, 0, 1}) || m.MetallicFactor != 0 || m.RoughnessFactor != 1 ||
+		m.BaseColorTexture != 0 || m.NormalTexture != -1 || m.EmissiveFactor != (mgl32.Vec3{0, 0, 1}) ||
+		m.AlphaMode != "MASK" || m.AlphaCutoff != 0.5 {
+		t.Errorf("material = %+v", m)
+	}
+	if tx := g.Textures[0]; tx.Image != 0 || tx.MagFilter != gl.NEAREST || tx.WrapS != gl.CLAMP_TO_EDGE || tx.WrapT != 0 {
+		t.Errorf("texture = %+v", tx)
+	}
+
+	if !reflect.DeepEqual(g.Roots, []int{0}) {
+		t.Errorf("roots = %v", g.Roots)
+	}
+	root, tri := g.Nodes[0], g.Nodes[1]
+	if root.Translation != (mgl32.Vec3{1, 2, 3}) || root.Mesh != -1 || !reflect.DeepEqual(root.Children, []int{1}) {
+		t.Errorf("root = %+v", root)
+	}
+	if tri.Translation != (mgl32.Vec3{5, 6, 7}) || tri.Scale != (mgl32.Vec3{2, 2, 2}) ||
+		!tri.Rotation.ApproxEqual(mgl32.QuatIdent()) || tri.Mesh != 0 {
+		t.Errorf("tri = %+v", tri)
+	}
+
+	a := g.Animations[0]
+	if a.Name != "move" || a.Duration != 2 || len(a.Channels) != 1 {
+		t.Fatalf("animation = %+v", a)
+	}
+	if got := a.Channels[0].Sample(0.5); !reflect.DeepEqual(got, []float32{1, 0.5, 0}) {
+		t.Errorf("translation at 0.5s = %v", got)
+	}
+}
+
+func TestParseGLTFDataURI(t *testing.T) {
+	text := fmt.Sprintf(triangleJSON, `"uri": "`+dataURI(triangleBuffer())+`", `)
+	g, err := ParseGLTF("models/triangle.gltf", []byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTriangle(t, g)
+	// image uris are unescaped and relative to the asset
+	if want := "models/textures/red brick.png"; g.Images[0].Path != want {
+		t.Errorf("image path = %q, want %q", g.Images[0].Path, want)
+	}
+}
+
+func TestParseGLB(t *testing.T) {
+	text := []byte(fmt.Sprintf(triangleJSON, ""))
+	for len(text)%4 != 0 {
+		text = append(text, ' ')
+	}
+	bin := triangleBuffer()
+	var glb bytes.Buffer
+	glb.WriteString(glbMagic)
+	binary.Write(&glb, binary.LittleEndian, []uint32{2, uint32(12 + 8 + len(text) + 8 + len(bin))})
+	binary.Write(&glb, binary.LittleEndian, uint32(len(text)))
+	glb.WriteString("JSON")
+	glb.Write(text)
+	binary.Write(&glb, binary.LittleEndian, uint32(len(bin)))
+	glb.WriteString("BIN\x00")
+	glb.Write(bin)
+
+	g, err := ParseGLTF("triangle.glb", glb.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTriangle(t, g)
+}
+
+func TestAnimationChannelSample(t *testing.T) {
+	half := float32(math.Sqrt(0.5))
+	rotation := &AnimationChannel{
+		Path:          "rotation",
+		Interpolation: "LINEAR",
+		Times:         []float32{1, 2},
+		// identity, then half a turn about z
+		Values:     []float32{0, 0, 0, 1, 0, 0, 1, 0},
+		Components: 4,
+	}
+	tests := []struct {
+		t    float32
+		want []float32
+	}{
+		{0, []float32{0, 0, 0, 1}},
+		{1.5, []float32{0, 0, half, half}},
+		{3, []float32{0, 0, 1, 0}},
+	}
+	for _, test := range tests {
+		got := rotation.Sample(test.t)
+		for i := range got {
+			if math.Abs(float64(got[i]-test.want[i])) > 1e-5 {
+				t.Errorf("rotation at %vs = %v, want %v", test.t, got, test.want)
+				break
+			}
+		}
+	}
+
+	step := &AnimationChannel{Path: "scale", Interpolation: "STEP", Times: []float32{0, 1},
+		Values: []float32{1, 1, 1, 2, 2, 2}, Components: 3}
+	if got := step.Sample(0.9); !reflect.DeepEqual(got, []float32{1, 1, 1}) {
+		t.Errorf("step at 0.9s = %v", got)
+	}
+
+	// with zero tangents a cubic spline eases between the values
+	cubic := &AnimationChannel{Path: "translation", Interpolation: "CUBICSPLINE", Times: []float32{0, 1},
+		Values: []float32{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 4, 0, 0, 0, 0, 0}, Components: 3}
+	if got := cubic.Sample(0.5); !reflect.DeepEqual(got, []float32{2, 0, 0}) {
+		t.Errorf("cubic at 0.5s = %v", got)
+	}
+	if got := cubic.Sample(0.25); got[0] >= 1 {
+		t.Errorf("cubic at 0.25s = %v, want eased below 1", got)
+	}
+}
+
+func TestParseGLTFSkin(t *testing.T) {
+	var b bytes.Buffer
+	for _, m := range []mgl32.Mat4{mgl32.Ident4(), mgl32.Translate3D(0, -1, 0)} {
+		binary.Write(&b, binary.LittleEndian, m)
+	}
+	text := `{
+		"asset": {"version": "2.0"},
+		"nodes": [{"children": [1]}, {"translation": [0, 1, 0]}, {"skin": 0}],
+		"skins": [{"joints": [0, 1], "inverseBindMatrices": 0, "skeleton": 0}],
+		"accessors": [{"bufferView": 0, "componentType": 5126, "count": 2, "type": "MAT4"}],
+		"bufferViews": [{"buffer": 0, "byteLength": 128}],
+		"buffers": [{"uri": "` + dataURI(b.Bytes()) + `", "byteLength": 128}]
+	}`
+	g, err := ParseGLTF("skin.gltf", []byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// without a scene the roots are the nodes without parents
+	if !reflect.DeepEqual(g.Roots, []int{0, 2}) {
+		t.Errorf("roots = %v", g.Roots)
+	}
+	skin := g.Skins[0]
+	if !reflect.DeepEqual(skin.Joints, []int{0, 1}) || skin.Skeleton != 0 || g.Nodes[2].Skin != 0 {
+		t.Errorf("skin = %+v", skin)
+	}
+	if len(skin.InverseBindMatrices) != 2 || skin.InverseBindMatrices[1] != mgl32.Translate3D(0, -1, 0) {
+		t.Errorf("inverse bind matrices = %v", skin.InverseBindMatrices)
+	}
+}
+
+func TestSceneSkinnedNode(t *testing.T) {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, mgl32.Translate3D(0, -1, 0))
+	// the skinned node is moved away from the origin, which must not move
+	// the mesh: only its joint does
+	text := `{
+		"asset": {"version": "2.0"},
+		"nodes": [{"translation": [0, 3, 0]}, {"skin": 0, "translation": [5, 0, 0]}],
+		"skins": [{"joints": [0], "inverseBindMatrices": 0}],
+		"accessors": [{"bufferView": 0, "componentType": 5126, "count": 1, "type": "MAT4"}],
+		"bufferViews": [{"buffer": 0, "byteLength": 64}],
+		"buffers": [{"uri": "` + dataURI(b.Bytes()) + `", "byteLength": 64}]
+	}`
+	g, err := ParseGLTF("skin.gltf", []byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := &Scene{Asset: g}
+	sc.buildNodes()
+	joint, skinned := sc.Nodes[0], sc.Nodes[1]
+	if skinned.Model() != mgl32.Ident4() {
+		t.Errorf("skinned model = %v, want the identity", skinned.Model())
+	}
+	if joint.Model() != mgl32.Translate3D(0, 3, 0) {
+		t.Errorf("joint model = %v", joint.Model())
+	}
+	// a vertex bound at the joint's bind pose, y = 1, follows it to y = 3
+	world := skinned.Model().Mul4(sc.JointMatrices(0)[0]).Mul4x1(mgl32.Vec4{0, 1, 0, 1})
+	if !world.ApproxEqual(mgl32.Vec4{0, 3, 0, 1}) {
+		t.Errorf("skinned vertex = %v, want (0, 3, 0)", world)
+	}
+}
+
+func TestParseGLTFErrors(t *testing.T) {
+	valid := fmt.Sprintf(triangleJSON, `"uri": "`+dataURI(triangleBuffer())+`", `)
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"syntax", "{\n\"asset\": {\"version\": \"2.0\"},\n\"nodes\": [}\n", "bad.gltf:3"},
+		{"version", `{"asset": {"version": "1.0"}}`, "unsupported glTF version"},
+		{"extension", `{"asset": {"version": "2.0"}, "extensionsRequired": ["KHR_draco_mesh_compression"]}`, "KHR_draco"},
+		{"child", strings.Replace(valid, `"children": [1]`, `"children": [7]`, 1), "child 7 out of range"},
+		{"cycle", strings.Replace(valid, `"mesh": 0, "matrix"`, `"mesh": 0, "children": [0], "matrix"`, 1), "own ancestor"},
+		{"material", strings.Replace(valid, `"material": 0`, `"material": 3`, 1), "material 3 out of range"},
+		{"texture", strings.Replace(valid, `"baseColorTexture": {"index": 0}`, `"baseColorTexture": {"index": -1}`, 1), "texture -1 out of range"},
+		{"count", strings.Replace(valid, `"count": 3, "type": "VEC3"`, `"count": -1, "type": "VEC3"`, 1), "negative count"},
+		{"offset", strings.Replace(valid, `{"bufferView": 0, `, `{"bufferView": 0, "byteOffset": -12, `, 1), "negative byteOffset"},
+		{"negative stride", strings.Replace(valid, `"byteLength": 36}`, `"byteLength": 36, "byteStride": -12}`, 1), "byteStride -12"},
+		{"short stride", strings.Replace(valid, `"byteLength": 36}`, `"byteLength": 36, "byteStride": 4}`, 1), "byteStride 4"},
+		{"buffer length", strings.Replace(valid, `"byteLength": 100`, `"byteLength": -1`, 1), "negative byteLength"},
+		{"huge stride", strings.Replace(valid, `"byteLength": 36}`, `"byteLength": 36, "byteStride": 4611686018427387904}`, 1), "past the end"},
+		{"huge view", strings.Replace(valid, `"byteOffset": 36, "byteLength": 24}`, `"byteOffset": 36, "byteLength": 9223372036854775800}`, 1), "past the end"},
+		{"overrun", strings.Replace(valid, `"count": 3, "type": "VEC3"`, `"count": 4, "type": "VEC3"`, 1), "past the end"},
+		{"buffer", strings.Replace(valid, `"byteLength": 100`, `"byteLength": 200`, 1), "buffer 0 has 100 bytes"},
+		{"missing", fmt.Sprintf(triangleJSON, `"uri": "missing.bin", `), "file not found"},
+		{"sparse", strings.Replace(valid, `"type": "SCALAR"}`, `"type": "SCALAR", "sparse": {}}`, 1), "sparse"},
+		{"glb", glbMagic + "\x01\x00\x00\x00", "truncated glb header"},
+	}
+	for _, test := range tests {
+		_, err := ParseGLTF("bad.gltf", []byte(test.text))
+		if _, ok := err.(*ModelError); !ok {
+			t.Errorf("%s: got %v, want a ModelError", test.name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %q, want it to contain %q", test.name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package golden checks rendered frames against reference images, so
+// changes to buffers or shaders can't silently change what a chapter draws.
+//
+// A chapter's test renders its first frame headless at a fixed time:
+//
+//	func TestGolden(t *testing.T) {
+//		golden.Chapter(t, main)
+//	}
+//
+// and compares it with testdata/golden.png. Headless rendering needs the
+// egl build tag, so run go test -tags egl; without it the tests are
+// skipped. Add -update to write the reference images after an intended
+// change.
+package golden
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mrbeskin/shader-learning/render"
+)
+
+var update = flag.Bool("update", false, "write the rendered frames as the new golden images")
+
+// Time is the clock time, in seconds, chapters are rendered at
+const Time = 1.0
+
+// Threshold is the largest perceptual difference between two pixels, from
+// 0 to 1, for them to count as the same. Software and hardware
+// rasterizers differ slightly at edges and in filtering.
+var Threshold = 0.1
+
+// MaxMismatch is the fraction of pixels allowed to differ before an image
+// fails to match
+var MaxMismatch = 0.001
+
+// Chapter runs a chapter's main function on a headless window for one frame
+// at Time, and checks the frame against testdata/golden.png. The test is
+// skipped if there is no headless gl context, e.g. when built without the
+// egl tag or EGL is missing.
+//
+// main exits the test binary on errors, as chapters use log.Fatalln.
+func Chapter(t *testing.T, main func()) {
+	t.Helper()
+	// gl contexts are current on a single OS thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	probe, err := render.NewHeadlessWindow(1, 1, "probe")
+	if err != nil {
+		t.Skip("no headless gl context:", err)
+	}
+	probe.Destroy()
+
+	frame := filepath.Join(t.TempDir(), "frame.png")
+	setFlags(t, map[string]string{
+		"backend":       string(render.EGLBackend),
+		"frames":        "1",
+		"capture-frame": "1",
+		"capture-path":  frame,
+		"time":          fmt.Sprint(Time),
+	})
+	main()
+
+	got, err := Load(frame)
+	if err != nil {
+		t.Fatalf("chapter did not save its frame: %v", err)
+	}
+	Check(t, filepath.Join("testdata", "golden.png"), got)
+}
+
+// setFlags sets flags of the render package, through a flag set of its
+// own so it works whether or not the chapter registers them
+func setFlags(t *testing.T, values map[string]string) {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	render.RegisterFlags(fs)
+	for name, value := range values {
+		if err := fs.Set(name, value); err != nil {
+			t.Fatalf("setting -%s: %v", name, err)
+		}
+	}
+}
+
+// Check compares got with the reference image at path. If they differ, the
+// test fails and the rendered image and a diff image are written next to
+// the reference, as name.got.png and name.diff.png. With -update, got is
+// written as the new reference instead.
+func Check(t *testing.T, path string, got image.Image) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := render.SavePNG(path, got); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("updated %s", path)
+		return
+	}
+
+	want, err := Load(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	diff, mismatched := Compare(want, got)
+	if diff == nil {
+		t.Fatalf("%s is %v, rendered frame is %v", path, want.Bounds().Size(), got.Bounds().Size())
+	}
+	total := got.Bounds().Dx() * got.Bounds().Dy()
+	if float64(mismatched) <= MaxMismatch*float64(total) {
+		return
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for file, img := range map[string]image.Image{base + ".got.png": got, base + ".diff.png": diff} {
+		if err := render.SavePNG(file, img); err != nil {
+			t.Errorf("failed to save %s: %v", file, err)
+		}
+	}
+	t.Errorf("%d of %d pixels differ from %s, see %s.diff.png", mismatched, total, path, base)
+}
+
+// Load decodes a PNG file
+func Load(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// Compare counts the pixels whose perceptual difference is above Threshold,
+// and returns a diff image with those pixels in red over a faded copy of
+// want. It returns a nil image if the sizes differ.
+func Compare(want image.Image, got image.Image) (*image.RGBA, int) {
+	bounds := want.Bounds()
+	if bounds.Size() != got.Bounds().Size() {
+		return nil, 0
+	}
+	offset := got.Bounds().Min.Sub(bounds.Min)
+	diff := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	limit := Threshold * Threshold * maxDelta
+	mismatched := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			a := want.At(x, y)
+			b := got.At(x+offset.X, y+offset.Y)
+			dx, dy := x-bounds.Min.X, y-bounds.Min.Y
+			if delta(a, b) > limit {
+				mismatched++
+				diff.Set(dx, dy, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+			gray := uint8(255 - (255-luma(a))/4)
+			diff.Set(dx, dy, color.RGBA{gray, gray, gray, 255})
+		}
+	}
+	return diff, mismatched
+}
+
+// maxDelta is the largest value of delta, between black and white
+const maxDelta = 35215.0
+
+// delta is the squared distance between two colors in YIQ space, weighted
+// for how visible each component is, as in "Measuring perceived color
+// difference using YIQ NTSC transmission color space" (Kotsarenko and
+// Ramos, 2010). Colors are blended over white first, so transparent pixels
+// compare by how they would look.
+func delta(a color.Color, b color.Color) float64 {
+	ar, ag, ab := blend(a)
+	br, bg, bb := blend(b)
+	y := rgb2y(ar, ag, ab) - rgb2y(br, bg, bb)
+	i := rgb2i(ar, ag, ab) - rgb2i(br, bg, bb)
+	q := rgb2q(ar, ag, ab) - rgb2q(br, bg, bb)
+	return 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+}
+
+func blend(c color.Color) (float64, float64, float64) {
+	r, g, b, a := c.RGBA()
+	white := float64(0xffff-a) * 255 / 0xffff
+	return float64(r)*255/0xffff + white, float64(g)*255/0xffff + white, float64(b)*255/0xffff + white
+}
+
+func luma(c color.Color) uint8 {
+	r, g, b := blend(c)
+	return uint8(rgb2y(r, g, b))
+}
+
+func rgb2y(r float64, g float64, b float64) float64 {
+	return r*0.29889531 + g*0.58662247 + b*0.11448223
+}
+
+func rgb2i(r float64, g float64, b float64) float64 {
+	return r*0.59597799 - g*0.27417610 - b*0.32180189
+}
+
+func rgb2q(r float64, g float64, b float64) float64 {
+	return r*0.21147017 - g*0.52261711 + b*0.31114694
+}
@@ -0,0 +1,116 @@
+//go:build linux && egl
+// +build linux,egl
+
+package render
+
+/*
+#cgo LDFLAGS: -lEGL
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+
+typedef struct {
+	EGLDisplay display;
+	EGLContext context;
+	EGLSurface surface;
+} headlessEGL;
+
+// headlessInit creates a gl 3.3 core context with no window. It prefers
+// Mesa's surfaceless platform, which needs no display server at all, and
+// falls back to the default display with a 1x1 pbuffer surface.
+static const char *headlessInit(headlessEGL *h) {
+	h->display = EGL_NO_DISPLAY;
+	h->context = EGL_NO_CONTEXT;
+	h->surface = EGL_NO_SURFACE;
+
+	int surfaceless = 0;
+	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+		(PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+	if (getPlatformDisplay) {
+		h->display = getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+		surfaceless = h->display != EGL_NO_DISPLAY;
+	}
+	if (!surfaceless) {
+		h->display = eglGetDisplay(EGL_DEFAULT_DISPLAY);
+	}
+	if (h->display == EGL_NO_DISPLAY) {
+		return "no EGL display";
+	}
+	if (!eglInitialize(h->display, NULL, NULL)) {
+		return "eglInitialize failed";
+	}
+	if (!eglBindAPI(EGL_OPENGL_API)) {
+		return "EGL does not support desktop gl";
+	}
+
+	EGLint configAttribs[] = {
+		EGL_SURFACE_TYPE, EGL_PBUFFER_BIT,
+		EGL_RENDERABLE_TYPE, EGL_OPENGL_BIT,
+		EGL_NONE,
+	};
+	EGLConfig config;
+	EGLint count;
+	if (!eglChooseConfig(h->display, configAttribs, &config, 1, &count) || count == 0) {
+		return "no EGL config for desktop gl";
+	}
+	EGLint contextAttribs[] = {
+		EGL_CONTEXT_MAJOR_VERSION, 3,
+		EGL_CONTEXT_MINOR_VERSION, 3,
+		EGL_CONTEXT_OPENGL_PROFILE_MASK, EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT,
+		EGL_NONE,
+	};
+	h->context = eglCreateContext(h->display, config, EGL_NO_CONTEXT, contextAttribs);
+	if (h->context == EGL_NO_CONTEXT) {
+		return "could not create a gl 3.3 core context";
+	}
+	if (!surfaceless) {
+		EGLint pbufferAttribs[] = {EGL_WIDTH, 1, EGL_HEIGHT, 1, EGL_NONE};
+		h->surface = eglCreatePbufferSurface(h->display, config, pbufferAttribs);
+		if (h->surface == EGL_NO_SURFACE) {
+			return "could not create a pbuffer surface";
+		}
+	}
+	if (!eglMakeCurrent(h->display, h->surface, h->surface, h->context)) {
+		return "eglMakeCurrent failed";
+	}
+	return NULL;
+}
+
+static void headlessDestroy(headlessEGL *h) {
+	if (h->display == EGL_NO_DISPLAY) {
+		return;
+	}
+	eglMakeCurrent(h->display, EGL_NO_SURFACE, EGL_NO_SURFACE, EGL_NO_CONTEXT);
+	if (h->surface != EGL_NO_SURFACE) {
+		eglDestroySurface(h->display, h->surface);
+	}
+	if (h->context != EGL_NO_CONTEXT) {
+		eglDestroyContext(h->display, h->context);
+	}
+	eglTerminate(h->display);
+}
+*/
+import "C"
+
+import (
+	"errors"
+)
+
+// eglContext is a gl context with no window
+type eglContext struct {
+	egl C.headlessEGL
+}
+
+// newEGLContext creates a gl context and makes it current on the calling
+// thread
+func newEGLContext() (*eglContext, error) {
+	c := &eglContext{}
+	if msg := C.headlessInit(&c.egl); msg != nil {
+		C.headlessDestroy(&c.egl)
+		return nil, errors.New(C.GoString(msg))
+	}
+	return c, nil
+}
+
+func (c *eglContext) destroy() {
+	C.headlessDestroy(&c.egl)
+}
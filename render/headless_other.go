@@ -0,0 +1,16 @@
+//go:build !linux || !egl
+// +build !linux !egl
+
+package render
+
+import (
+	"errors"
+)
+
+type eglContext struct{}
+
+func newEGLContext() (*eglContext, error) {
+	return nil, errors.New("headless rendering needs linux and the egl build tag")
+}
+
+func (c *eglContext) destroy() {}
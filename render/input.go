@@ -0,0 +1,340 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// DefaultBindings are the action bindings every Input starts with, in the
+// bindings file format: one "action = input input..." line per action,
+// with # comments. Inputs are key names such as Escape, R, F1 or Space,
+// and MouseLeft, MouseRight or MouseMiddle.
+const DefaultBindings = `
+quit       = Escape
+reload     = R
+pause      = Space
+wireframe  = F1
+screenshot = F12
+`
+
+// Binding is a key or mouse button bound to an action
+type Binding struct {
+	Key    glfw.Key
+	Mouse  bool
+	Button glfw.MouseButton
+}
+
+// Input records the keyboard, mouse and scroll state of a window frame by
+// frame, and maps named actions to keys and mouse buttons. Update must be
+// called once a frame, after events are polled.
+type Input struct {
+	window *Window
+
+	keys    buttonStates
+	buttons buttonStates
+
+	scrollX, scrollY   float64
+	pendingX, pendingY float64
+	cursorX, cursorY   float64
+	lastX, lastY       float64
+
+	bindings map[string][]Binding
+	handlers map[string][]func()
+}
+
+// buttonStates tracks which keys or buttons are down, and which went down
+// or up since the last frame. pending collects events until Update.
+type buttonStates struct {
+	down                            map[int]bool
+	pressed, released               map[int]bool
+	pendingPressed, pendingReleased map[int]bool
+}
+
+func newButtonStates() buttonStates {
+	return buttonStates{
+		down:            map[int]bool{},
+		pressed:         map[int]bool{},
+		released:        map[int]bool{},
+		pendingPressed:  map[int]bool{},
+		pendingReleased: map[int]bool{},
+	}
+}
+
+func (b *buttonStates) event(id int, action glfw.Action) {
+	switch action {
+	case glfw.Press:
+		b.down[id] = true
+		b.pendingPressed[id] = true
+	case glfw.Release:
+		b.down[id] = false
+		b.pendingReleased[id] = true
+	}
+}
+
+func (b *buttonStates) update() {
+	b.pressed, b.pendingPressed = b.pendingPressed, map[int]bool{}
+	b.released, b.pendingReleased = b.pendingReleased, map[int]bool{}
+}
+
+// NewInput starts recording input from w, replacing its key, mouse button
+// and scroll callbacks. Actions are bound from DefaultBindings and then
+// from the user's bindings file, if there is one (see UserBindingsPath).
+func NewInput(w *Window) (*Input, error) {
+	in := &Input{
+		window:   w,
+		keys:     newButtonStates(),
+		buttons:  newButtonStates(),
+		bindings: map[string][]Binding{},
+		handlers: map[string][]func(){},
+	}
+	if err := in.ParseBindings("<default bindings>", DefaultBindings); err != nil {
+		return nil, err
+	}
+	if path, err := UserBindingsPath(); err == nil {
+		if err := in.LoadBindings(path); err != nil {
+			if _, missing := err.(*FileNotFoundError); !missing {
+				return nil, err
+			}
+		}
+	}
+	in.cursorX, in.cursorY = w.GetCursorPos()
+	in.lastX, in.lastY = in.cursorX, in.cursorY
+
+	w.SetKeyCallback(func(_ *glfw.Window, key glfw.Key, _ int, action glfw.Action, _ glfw.ModifierKey) {
+		in.keys.event(int(key), action)
+	})
+	w.SetMouseButtonCallback(func(_ *glfw.Window, button glfw.MouseButton, action glfw.Action, _ glfw.ModifierKey) {
+		in.buttons.event(int(button), action)
+	})
+	w.SetScrollCallback(func(_ *glfw.Window, x float64, y float64) {
+		in.pendingX += x
+		in.pendingY += y
+	})
+	return in, nil
+}
+
+// UserBindingsPath returns the path of the user's bindings file,
+// shader-learning/bindings.conf in the user config directory
+func UserBindingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "shader-learning", "bindings.conf"), nil
+}
+
+// Update moves the events received since the last call into the current
+// frame and runs the handlers of actions that were pressed
+func (in *Input) Update() {
+	in.keys.update()
+	in.buttons.update()
+	in.scrollX, in.scrollY, in.pendingX, in.pendingY = in.pendingX, in.pendingY, 0, 0
+	in.lastX, in.lastY = in.cursorX, in.cursorY
+	in.cursorX, in.cursorY = in.window.GetCursorPos()
+
+	for _, action := range in.actions() {
+		if in.ActionPressed(action) {
+			for _, fn := range in.handlers[action] {
+				fn()
+			}
+		}
+	}
+}
+
+// Pressed reports whether key went down this frame
+func (in *Input) Pressed(key glfw.Key) bool {
+	return in.keys.pressed[int(key)]
+}
+
+// Held reports whether key is down
+func (in *Input) Held(key glfw.Key) bool {
+	return in.keys.down[int(key)]
+}
+
+// Released reports whether key went up this frame
+func (in *Input) Released(key glfw.Key) bool {
+	return in.keys.released[int(key)]
+}
+
+// MousePressed reports whether button went down this frame
+func (in *Input) MousePressed(button glfw.MouseButton) bool {
+	return in.buttons.pressed[int(button)]
+}
+
+// MouseHeld reports whether button is down
+func (in *Input) MouseHeld(button glfw.MouseButton) bool {
+	return in.buttons.down[int(button)]
+}
+
+// MouseReleased reports whether button went up this frame
+func (in *Input) MouseReleased(button glfw.MouseButton) bool {
+	return in.buttons.released[int(button)]
+}
+
+// Scroll returns the scroll offset received this frame
+func (in *Input) Scroll() (x float64, y float64) {
+	return in.scrollX, in.scrollY
+}
+
+// Cursor returns the cursor position in screen coordinates
+func (in *Input) Cursor() (x float64, y float64) {
+	return in.cursorX, in.cursorY
+}
+
+// CursorDelta returns how far the cursor moved since the last frame
+func (in *Input) CursorDelta() (dx float64, dy float64) {
+	return in.cursorX - in.lastX, in.cursorY - in.lastY
+}
+
+// Bind replaces the bindings of an action
+func (in *Input) Bind(action string, bindings ...Binding) {
+	in.bindings[action] = bindings
+}
+
+// OnAction registers fn to run in Update on frames where action is pressed
+func (in *Input) OnAction(action string, fn func()) {
+	in.handlers[action] = append(in.handlers[action], fn)
+}
+
+// ActionPressed reports whether any input bound to action went down this
+// frame
+func (in *Input) ActionPressed(action string) bool {
+	return in.anyBinding(action, in.Pressed, in.MousePressed)
+}
+
+// ActionHeld reports whether any input bound to action is down
+func (in *Input) ActionHeld(action string) bool {
+	return in.anyBinding(action, in.Held, in.MouseHeld)
+}
+
+// ActionReleased reports whether any input bound to action went up this
+// frame
+func (in *Input) ActionReleased(action string) bool {
+	return in.anyBinding(action, in.Released, in.MouseReleased)
+}
+
+func (in *Input) anyBinding(action string, key func(glfw.Key) bool, mouse func(glfw.MouseButton) bool) bool {
+	for _, b := range in.bindings[action] {
+		if b.Mouse && mouse(b.Button) || !b.Mouse && key(b.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// actions returns the bound action names in sorted order, so handlers run
+// in a stable order
+func (in *Input) actions() []string {
+	var actions []string
+	for action := range in.bindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// LoadBindings reads a bindings file (see DefaultBindings for the format).
+// Each action it lists replaces that action's bindings.
+func (in *Input) LoadBindings(path string) error {
+	text, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	return in.ParseBindings(path, text)
+}
+
+// ParseBindings parses bindings held in memory. name is used in errors.
+func (in *Input) ParseBindings(name string, text string) error {
+	parsed := map[string][]Binding{}
+	for i, line := range strings.Split(text, "\n") {
+		diag := Diagnostic{
+			Severity: SeverityError,
+			File:     name,
+			Line:     i + 1,
+			Source:   line,
+		}
+		if c := strings.Index(line, "#"); c >= 0 {
+			line = line[:c]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		eq := strings.Index(line, "=")
+		action := ""
+		if eq >= 0 {
+			action = strings.TrimSpace(line[:eq])
+		}
+		if action == "" {
+			diag.Message = "expected action = input..."
+			return &ConfigError{diag}
+		}
+		var bindings []Binding
+		for _, input := range strings.Fields(line[eq+1:]) {
+			b, ok := bindingNames[strings.ToLower(input)]
+			if !ok {
+				diag.Message = fmt.Sprintf("unknown key or mouse button %q", input)
+				return &ConfigError{diag}
+			}
+			bindings = append(bindings, b)
+		}
+		parsed[action] = bindings
+	}
+	for action, bindings := range parsed {
+		in.bindings[action] = bindings
+	}
+	return nil
+}
+
+// bindingNames maps lower case input names to bindings
+var bindingNames = func() map[string]Binding {
+	names := map[string]Binding{}
+	key := func(name string, k glfw.Key) {
+		names[strings.ToLower(name)] = Binding{Key: k}
+	}
+	for i := 0; i < 26; i++ {
+		key(string(rune('A'+i)), glfw.KeyA+glfw.Key(i))
+	}
+	for i := 0; i < 10; i++ {
+		key(string(rune('0'+i)), glfw.Key0+glfw.Key(i))
+	}
+	for i := 0; i < 12; i++ {
+		key(fmt.Sprintf("F%d", i+1), glfw.KeyF1+glfw.Key(i))
+	}
+	for name, k := range map[string]glfw.Key{
+		"Space":        glfw.KeySpace,
+		"Escape":       glfw.KeyEscape,
+		"Enter":        glfw.KeyEnter,
+		"Tab":          glfw.KeyTab,
+		"Backspace":    glfw.KeyBackspace,
+		"Insert":       glfw.KeyInsert,
+		"Delete":       glfw.KeyDelete,
+		"Right":        glfw.KeyRight,
+		"Left":         glfw.KeyLeft,
+		"Down":         glfw.KeyDown,
+		"Up":           glfw.KeyUp,
+		"PageUp":       glfw.KeyPageUp,
+		"PageDown":     glfw.KeyPageDown,
+		"Home":         glfw.KeyHome,
+		"End":          glfw.KeyEnd,
+		"LeftShift":    glfw.KeyLeftShift,
+		"RightShift":   glfw.KeyRightShift,
+		"LeftControl":  glfw.KeyLeftControl,
+		"RightControl": glfw.KeyRightControl,
+		"LeftAlt":      glfw.KeyLeftAlt,
+		"RightAlt":     glfw.KeyRightAlt,
+		"Minus":        glfw.KeyMinus,
+		"Equal":        glfw.KeyEqual,
+		"GraveAccent":  glfw.KeyGraveAccent,
+	} {
+		key(name, k)
+	}
+	names["mouseleft"] = Binding{Mouse: true, Button: glfw.MouseButtonLeft}
+	names["mouseright"] = Binding{Mouse: true, Button: glfw.MouseButtonRight}
+	names["mousemiddle"] = Binding{Mouse: true, Button: glfw.MouseButtonMiddle}
+	return names
+}()
@@ -0,0 +1,110 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// newTestInput returns an Input with the default bindings and no window.
+// Frames are advanced with endFrame in place of Update.
+func newTestInput(t *testing.T) *Input {
+	t.Helper()
+	in := &Input{
+		keys:     newButtonStates(),
+		buttons:  newButtonStates(),
+		bindings: map[string][]Binding{},
+		handlers: map[string][]func(){},
+	}
+	if err := in.ParseBindings("<default bindings>", DefaultBindings); err != nil {
+		t.Fatal(err)
+	}
+	return in
+}
+
+func (in *Input) endFrame() {
+	in.keys.update()
+	in.buttons.update()
+}
+
+func TestParseBindingsOverrides(t *testing.T) {
+	in := newTestInput(t)
+	err := in.ParseBindings("bindings.conf", `
+# quit with q or the right mouse button
+quit = Q MouseRight
+reload =
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Binding{{Key: glfw.KeyQ}, {Mouse: true, Button: glfw.MouseButtonRight}}
+	if got := in.bindings["quit"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("quit = %v, want %v", got, want)
+	}
+	if got := in.bindings["reload"]; len(got) != 0 {
+		t.Errorf("reload = %v, want unbound", got)
+	}
+	if got := in.bindings["pause"]; !reflect.DeepEqual(got, []Binding{{Key: glfw.KeySpace}}) {
+		t.Errorf("pause = %v, want the default Space", got)
+	}
+}
+
+func TestParseBindingsErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		line int
+	}{
+		{"quit = Escape\nreload R\n", 2},
+		{"\n\n= Escape\n", 3},
+		{"quit = Escape\n# comment\npause = Spacebar\n", 3},
+	}
+	for _, test := range tests {
+		in := newTestInput(t)
+		err := in.ParseBindings("bindings.conf", test.text)
+		configErr, ok := err.(*ConfigError)
+		if !ok {
+			t.Errorf("%q: got %v, want a ConfigError", test.text, err)
+			continue
+		}
+		if configErr.File != "bindings.conf" || configErr.Line != test.line {
+			t.Errorf("%q: error at %s:%d, want bindings.conf:%d", test.text, configErr.File, configErr.Line, test.line)
+		}
+		// a bad file changes no bindings
+		if got := in.bindings["quit"]; !reflect.DeepEqual(got, []Binding{{Key: glfw.KeyEscape}}) {
+			t.Errorf("%q: quit = %v, want the default Escape", test.text, got)
+		}
+	}
+}
+
+func TestInputTransitions(t *testing.T) {
+	in := newTestInput(t)
+	type state struct{ pressed, held, released bool }
+	frames := []struct {
+		events []glfw.Action
+		want   state
+	}{
+		{nil, state{}},
+		{[]glfw.Action{glfw.Press}, state{pressed: true, held: true}},
+		{[]glfw.Action{glfw.Repeat}, state{held: true}},
+		{nil, state{held: true}},
+		{[]glfw.Action{glfw.Release}, state{released: true}},
+		{nil, state{}},
+		// a tap within one frame is both pressed and released
+		{[]glfw.Action{glfw.Press, glfw.Release}, state{pressed: true, released: true}},
+	}
+	for i, frame := range frames {
+		for _, action := range frame.events {
+			in.keys.event(int(glfw.KeySpace), action)
+		}
+		in.endFrame()
+		got := state{in.Pressed(glfw.KeySpace), in.Held(glfw.KeySpace), in.Released(glfw.KeySpace)}
+		if got != frame.want {
+			t.Errorf("frame %d: got %+v, want %+v", i, got, frame.want)
+		}
+		action := state{in.ActionPressed("pause"), in.ActionHeld("pause"), in.ActionReleased("pause")}
+		if action != frame.want {
+			t.Errorf("frame %d: pause action %+v, want %+v", i, action, frame.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// variable is an in or out declaration found in shader source
+type variable struct {
+	Type string
+	Name string
+	Line int
+}
+
+// matches e.g. "layout (location = 0) in vec3 aPos;" or "flat out int id;"
+var ioDeclaration = regexp.MustCompile(
+	`^\s*(?:layout\s*\([^)]*\)\s*)?(?:(?:flat|smooth|noperspective|centroid)\s+)*(in|out)\s+(\w+)\s+(\w+)\s*(?:\[[^\]]*\])?\s*;`)
+
+// checkInterface warns about inputs of one stage that the previous stage
+// does not write, or writes with a different type. Unmatched inputs are a
+// link error on some drivers and silently zero on others. The vertex
+// shader's inputs are attributes and are not checked.
+func checkInterface(producer *Source, from Stage, consumer *Source, to Stage) []Diagnostic {
+	outs := map[string]variable{}
+	for _, v := range declarations(producer.Text, "out") {
+		outs[v.Name] = v
+	}
+	var diags []Diagnostic
+	for _, in := range declarations(consumer.Text, "in") {
+		out, ok := outs[in.Name]
+		var msg string
+		switch {
+		case !ok:
+			msg = fmt.Sprintf("%s input '%s' has no matching %s shader output", to, in.Name, from)
+		case out.Type != in.Type:
+			msg = fmt.Sprintf("%s input '%s' is %s but the %s shader writes %s", to, in.Name, in.Type, from, out.Type)
+		default:
+			continue
+		}
+		file, line := consumer.locate(in.Line)
+		diags = append(diags, Diagnostic{
+			Severity: SeverityWarning,
+			File:     file,
+			Line:     line,
+			Message:  msg,
+			Source:   consumer.Line(file, line),
+		})
+	}
+	return diags
+}
+
+// declarations returns the global in or out variables declared in source.
+// Lines are lines of the expanded source.
+func declarations(source string, qualifier string) []variable {
+	var vars []variable
+	for i, line := range strings.Split(source, "\n") {
+		if c := strings.Index(line, "//"); c >= 0 {
+			line = line[:c]
+		}
+		m := ioDeclaration.FindStringSubmatch(line)
+		if m == nil || m[1] != qualifier {
+			continue
+		}
+		vars = append(vars, variable{Type: m[2], Name: m[3], Line: i + 1})
+	}
+	return vars
+}
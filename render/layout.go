@@ -0,0 +1,161 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// VertexAttribute is one attribute of an interleaved vertex
+type VertexAttribute struct {
+	// Name is the vertex shader input, e.g. aPos
+	Name     string
+	Location uint32
+	// Count is the number of components, 1 to 4
+	Count int32
+	// Type is the gl type of each component, e.g. gl.FLOAT or
+	// gl.UNSIGNED_BYTE. Integer types are converted to float inputs.
+	Type uint32
+	// Normalized maps integer types to 0..1, or -1..1 if signed
+	Normalized bool
+}
+
+// componentSizes are the sizes in bytes of vertex component types
+var componentSizes = map[uint32]int32{
+	gl.BYTE:           1,
+	gl.UNSIGNED_BYTE:  1,
+	gl.SHORT:          2,
+	gl.UNSIGNED_SHORT: 2,
+	gl.HALF_FLOAT:     2,
+	gl.INT:            4,
+	gl.UNSIGNED_INT:   4,
+	gl.FLOAT:          4,
+}
+
+// Size returns the size of the attribute in bytes
+func (a VertexAttribute) Size() int32 {
+	return a.Count * componentSizes[a.Type]
+}
+
+// VertexLayout describes how attributes are interleaved in a vertex buffer.
+// Attributes are packed in order, with no padding between them.
+type VertexLayout struct {
+	Attributes []VertexAttribute
+}
+
+// NewVertexLayout returns a layout of the given attributes, in order
+func NewVertexLayout(attributes ...VertexAttribute) *VertexLayout {
+	return &VertexLayout{Attributes: attributes}
+}
+
+// FloatLayout returns a layout of float attributes with the given component
+// counts, at locations 0, 1, 2 and so on. The attributes are unnamed, so
+// Check can only match them by location.
+func FloatLayout(counts ...int32) *VertexLayout {
+	l := &VertexLayout{}
+	for i, count := range counts {
+		l.Attributes = append(l.Attributes, VertexAttribute{Location: uint32(i), Count: count, Type: gl.FLOAT})
+	}
+	return l
+}
+
+// Stride returns the size of a vertex in bytes
+func (l *VertexLayout) Stride() int32 {
+	var stride int32
+	for _, a := range l.Attributes {
+		stride += a.Size()
+	}
+	return stride
+}
+
+// Offset returns the offset in bytes of the named attribute within a
+// vertex, or -1 if there is no such attribute
+func (l *VertexLayout) Offset(name string) int {
+	offset := 0
+	for _, a := range l.Attributes {
+		if a.Name == name {
+			return offset
+		}
+		offset += int(a.Size())
+	}
+	return -1
+}
+
+// Apply points the attributes of the bound vertex array at the buffer bound
+// to gl.ARRAY_BUFFER, and enables them
+func (l *VertexLayout) Apply() {
+	stride := l.Stride()
+	offset := 0
+	for _, a := range l.Attributes {
+		gl.VertexAttribPointer(a.Location, a.Count, a.Type, a.Normalized, stride, gl.PtrOffset(offset))
+		gl.EnableVertexAttribArray(a.Location)
+		offset += int(a.Size())
+	}
+}
+
+// Check compares the layout with the active vertex inputs of a program. Every
+// input must have an attribute of the same name, or of the same location
+// for unnamed attributes, with a matching location and component count.
+// Attributes the program doesn't use are ignored.
+func (l *VertexLayout) Check(s *Shader) error {
+	var count, maxLength int32
+	gl.GetProgramiv(s.ID, gl.ACTIVE_ATTRIBUTES, &count)
+	gl.GetProgramiv(s.ID, gl.ACTIVE_ATTRIBUTE_MAX_LENGTH, &maxLength)
+	if maxLength == 0 {
+		return nil
+	}
+	buf := make([]uint8, maxLength)
+	for i := uint32(0); i < uint32(count); i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetActiveAttrib(s.ID, i, maxLength, &length, &size, &xtype, &buf[0])
+		name := string(buf[:length])
+		if strings.HasPrefix(name, "gl_") {
+			continue
+		}
+		location := gl.GetAttribLocation(s.ID, gl.Str(name+"\x00"))
+		if err := l.checkInput(name, uint32(location), xtype); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *VertexLayout) checkInput(name string, location uint32, xtype uint32) error {
+	a, ok := l.attribute(name, location)
+	if !ok {
+		return &VertexLayoutError{Attribute: name, Message: fmt.Sprintf(
+			"%s input at location %d is not in the vertex layout", typeName(xtype), location)}
+	}
+	if a.Location != location {
+		return &VertexLayoutError{Attribute: name, Message: fmt.Sprintf(
+			"layout has location %d, shader has location %d", a.Location, location)}
+	}
+	t, ok := uniformTypes[xtype]
+	if ok && (t.kind == intKind || t.kind == uintKind || t.kind == doubleKind || t.kind == doubleMatrixKind) {
+		return &VertexLayoutError{Attribute: name, Message: fmt.Sprintf(
+			"%s inputs are not supported, layout attributes are converted to float", t.name)}
+	}
+	if ok && t.components != int(a.Count) {
+		return &VertexLayoutError{Attribute: name, Message: fmt.Sprintf(
+			"layout has %d components, shader input is %s", a.Count, t.name)}
+	}
+	return nil
+}
+
+// attribute finds the layout attribute for a shader input by name, or by
+// location among the unnamed attributes
+func (l *VertexLayout) attribute(name string, location uint32) (VertexAttribute, bool) {
+	for _, a := range l.Attributes {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	for _, a := range l.Attributes {
+		if a.Name == "" && a.Location == location {
+			return a, true
+		}
+	}
+	return VertexAttribute{}, false
+}
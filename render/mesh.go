@@ -0,0 +1,222 @@
+package render
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+const (
+	sizeof_float32 = 4
+	sizeof_uint16  = 2
+	sizeof_uint32  = 4
+)
+
+// Mesh owns the vertex array, vertex buffer and optional element buffer of
+// one object. A scene can hold any number of meshes.
+type Mesh struct {
+	VAO uint32
+	VBO uint32
+	EBO uint32
+
+	Layout *VertexLayout
+	// Mode is the kind of primitive drawn, gl.TRIANGLES by default
+	Mode uint32
+
+	usage       uint32
+	vertexCount int32
+	vertexBytes int
+	indexCount  int32
+	indexBytes  int
+	// indexType is gl.UNSIGNED_SHORT or gl.UNSIGNED_INT
+	indexType uint32
+}
+
+// NewMesh uploads interleaved vertices laid out as described by layout, and
+// optional indices. vertices is a slice of any fixed size type, e.g.
+// []float32, []byte or a slice of structs, whose size in bytes is a multiple
+// of the layout's stride. Indices are stored as 16 bit if they all fit, else
+// as 32 bit.
+func NewMesh(vertices interface{}, indices []uint32, layout *VertexLayout) (*Mesh, error) {
+	return newMesh(vertices, indices, layout, gl.STATIC_DRAW)
+}
+
+// NewDynamicMesh is like NewMesh, for meshes whose vertices or indices are
+// updated often, e.g. every frame
+func NewDynamicMesh(vertices interface{}, indices []uint32, layout *VertexLayout) (*Mesh, error) {
+	return newMesh(vertices, indices, layout, gl.DYNAMIC_DRAW)
+}
+
+func newMesh(vertices interface{}, indices []uint32, layout *VertexLayout, usage uint32) (*Mesh, error) {
+	if layout.Stride() == 0 {
+		return nil, &MeshError{Message: "vertex layout has no attributes"}
+	}
+	size, err := vertexBytes(vertices, layout)
+	if err != nil {
+		return nil, err
+	}
+	m := &Mesh{Layout: layout, Mode: gl.TRIANGLES, usage: usage}
+	gl.GenVertexArrays(1, &m.VAO)
+	gl.GenBuffers(1, &m.VBO)
+
+	// bind Vertex Array first
+	gl.BindVertexArray(m.VAO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.VBO)
+	m.uploadVertices(vertices, size)
+	if len(indices) > 0 {
+		gl.GenBuffers(1, &m.EBO)
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, m.EBO)
+		m.uploadIndices(indices)
+	}
+
+	layout.Apply()
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+
+	gl.BindVertexArray(0)
+	return m, nil
+}
+
+// vertexBytes returns the size of vertices in bytes, checking that it is a
+// slice holding whole vertices of layout
+func vertexBytes(vertices interface{}, layout *VertexLayout) (int, error) {
+	v := reflect.ValueOf(vertices)
+	if v.Kind() != reflect.Slice {
+		return 0, &MeshError{Message: fmt.Sprintf("vertices are a %T, not a slice", vertices)}
+	}
+	size := v.Len() * int(v.Type().Elem().Size())
+	if stride := int(layout.Stride()); size%stride != 0 {
+		return 0, &MeshError{Message: fmt.Sprintf("%d bytes of vertices are not a multiple of the %d byte stride", size, stride)}
+	}
+	return size, nil
+}
+
+// VertexCount returns the number of vertices in the vertex buffer
+func (m *Mesh) VertexCount() int {
+	return int(m.vertexCount)
+}
+
+// IndexCount returns the number of indices, 0 if the mesh has none
+func (m *Mesh) IndexCount() int {
+	return int(m.indexCount)
+}
+
+// SetVertices replaces the vertices of the mesh, which are as for NewMesh.
+// The buffer is reused if the vertices fit, and reallocated if not.
+func (m *Mesh) SetVertices(vertices interface{}) error {
+	size, err := vertexBytes(vertices, m.Layout)
+	if err != nil {
+		return err
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.VBO)
+	m.uploadVertices(vertices, size)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	return nil
+}
+
+// UpdateVertices overwrites vertices starting at the given vertex, e.g. to
+// move a few vertices without uploading the whole mesh. The vertices must
+// fit in the buffer.
+func (m *Mesh) UpdateVertices(first int, vertices interface{}) error {
+	size, err := vertexBytes(vertices, m.Layout)
+	if err != nil {
+		return err
+	}
+	offset := first * int(m.Layout.Stride())
+	if first < 0 || offset+size > m.vertexBytes {
+		return &MeshError{Message: fmt.Sprintf("%d bytes of vertices at vertex %d do not fit in the %d byte buffer",
+			size, first, m.vertexBytes)}
+	}
+	if size == 0 {
+		return nil
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.VBO)
+	gl.BufferSubData(gl.ARRAY_BUFFER, offset, size, gl.Ptr(vertices))
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	return nil
+}
+
+// SetIndices replaces the indices of the mesh, adding an element buffer if
+// it had none. An empty slice draws the vertices in order.
+func (m *Mesh) SetIndices(indices []uint32) {
+	if len(indices) == 0 {
+		m.indexCount = 0
+		return
+	}
+	// the element buffer binding is part of the vertex array
+	gl.BindVertexArray(m.VAO)
+	if m.EBO == 0 {
+		gl.GenBuffers(1, &m.EBO)
+	}
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, m.EBO)
+	m.uploadIndices(indices)
+	gl.BindVertexArray(0)
+}
+
+// uploadVertices fills the bound vertex buffer with size bytes of vertices
+func (m *Mesh) uploadVertices(vertices interface{}, size int) {
+	m.vertexCount = int32(size) / m.Layout.Stride()
+	if size == 0 {
+		return
+	}
+	if size > m.vertexBytes {
+		gl.BufferData(gl.ARRAY_BUFFER, size, gl.Ptr(vertices), m.usage)
+		m.vertexBytes = size
+	} else {
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, size, gl.Ptr(vertices))
+	}
+}
+
+// uploadIndices fills the bound element buffer, as 16 bit indices if they
+// all fit
+func (m *Mesh) uploadIndices(indices []uint32) {
+	m.indexCount = int32(len(indices))
+	var data interface{} = indices
+	size := len(indices) * sizeof_uint32
+	m.indexType = gl.UNSIGNED_INT
+	if maxIndex(indices) <= 0xffff {
+		short := make([]uint16, len(indices))
+		for i, index := range indices {
+			short[i] = uint16(index)
+		}
+		size = len(indices) * sizeof_uint16
+		m.indexType = gl.UNSIGNED_SHORT
+		data = short
+	}
+	if size > m.indexBytes {
+		gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, size, gl.Ptr(data), m.usage)
+		m.indexBytes = size
+	} else {
+		gl.BufferSubData(gl.ELEMENT_ARRAY_BUFFER, 0, size, gl.Ptr(data))
+	}
+}
+
+func maxIndex(indices []uint32) uint32 {
+	var largest uint32
+	for _, index := range indices {
+		if index > largest {
+			largest = index
+		}
+	}
+	return largest
+}
+
+// Draw draws the mesh
+func (m *Mesh) Draw() {
+	gl.BindVertexArray(m.VAO)
+	if m.indexCount > 0 {
+		gl.DrawElements(m.Mode, m.indexCount, m.indexType, gl.PtrOffset(0))
+	} else {
+		gl.DrawArrays(m.Mode, 0, m.vertexCount)
+	}
+	gl.BindVertexArray(0)
+}
+
+// Delete frees the gl buffers and vertex array
+func (m *Mesh) Delete() {
+	gl.DeleteVertexArrays(1, &m.VAO)
+	gl.DeleteBuffers(1, &m.VBO)
+	if m.EBO != 0 {
+		gl.DeleteBuffers(1, &m.EBO)
+	}
+}
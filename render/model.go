@@ -0,0 +1,166 @@
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ModelLayout is the vertex layout of loaded models: position, normal and
+// texture coordinates, at locations 0, 1 and 2
+var ModelLayout = NewVertexLayout(
+	VertexAttribute{Name: "aPos", Location: 0, Count: 3, Type: gl.FLOAT},
+	VertexAttribute{Name: "aNormal", Location: 1, Count: 3, Type: gl.FLOAT},
+	VertexAttribute{Name: "aTexCoord", Location: 2, Count: 2, Type: gl.FLOAT},
+)
+
+// MeshData is the vertices and indices of a mesh before it is uploaded to
+// gl, e.g. as parsed from a model file
+type MeshData struct {
+	Vertices []float32
+	Indices  []uint32
+	Layout   *VertexLayout
+}
+
+// VertexCount returns the number of vertices
+func (d *MeshData) VertexCount() int {
+	stride := int(d.Layout.Stride())
+	if stride == 0 {
+		return 0
+	}
+	return len(d.Vertices) * sizeof_float32 / stride
+}
+
+// Upload creates a Mesh from the data
+func (d *MeshData) Upload() (*Mesh, error) {
+	return NewMesh(d.Vertices, d.Indices, d.Layout)
+}
+
+// Material is a Phong material, as described by an MTL file. Texture maps
+// are file paths, relative to the working directory.
+type Material struct {
+	Name     string
+	Ambient  mgl32.Vec3
+	Diffuse  mgl32.Vec3
+	Specular mgl32.Vec3
+	Emissive mgl32.Vec3
+	// Shininess is the specular exponent
+	Shininess float32
+	// Opacity is 1 for opaque materials
+	Opacity float32
+
+	AmbientMap  string
+	DiffuseMap  string
+	SpecularMap string
+	NormalMap   string
+	OpacityMap  string
+}
+
+// NewMaterial returns a white, opaque material
+func NewMaterial(name string) *Material {
+	return &Material{
+		Name:      name,
+		Ambient:   mgl32.Vec3{1, 1, 1},
+		Diffuse:   mgl32.Vec3{1, 1, 1},
+		Shininess: 1,
+		Opacity:   1,
+	}
+}
+
+// Model is a set of meshes uploaded to gl, each with its material and the
+// material's textures
+type Model struct {
+	Parts []*ModelPart
+	// white is bound in place of missing textures
+	white *Texture
+}
+
+// ModelPart is a mesh drawn with one material. Textures are nil if the
+// material has no such map.
+type ModelPart struct {
+	Name            string
+	Mesh            *Mesh
+	Material        *Material
+	DiffuseTexture  *Texture
+	SpecularTexture *Texture
+	NormalTexture   *Texture
+}
+
+// Draw draws every part, binding its diffuse, specular and normal textures
+// to texture units 0, 1 and 2. Missing textures are bound as 1x1 white, or
+// as texture 0 if the model was not loaded by LoadOBJ, so a part never
+// samples the textures of the part drawn before it.
+func (m *Model) Draw() {
+	for _, part := range m.Parts {
+		for i, tx := range []*Texture{part.DiffuseTexture, part.SpecularTexture, part.NormalTexture} {
+			unit := gl.TEXTURE0 + uint32(i)
+			switch {
+			case tx != nil:
+				tx.Bind(unit)
+			case m.white != nil:
+				m.white.Bind(unit)
+			default:
+				gl.ActiveTexture(unit)
+				gl.BindTexture(gl.TEXTURE_2D, 0)
+			}
+		}
+		part.Mesh.Draw()
+	}
+}
+
+// Textures returns the textures of the model, each once, e.g. to add them
+// to Assets for hot reloading
+func (m *Model) Textures() []*Texture {
+	var textures []*Texture
+	seen := map[*Texture]bool{}
+	for _, part := range m.Parts {
+		for _, tx := range []*Texture{part.DiffuseTexture, part.SpecularTexture, part.NormalTexture} {
+			if tx != nil && !seen[tx] {
+				seen[tx] = true
+				textures = append(textures, tx)
+			}
+		}
+	}
+	return textures
+}
+
+// Delete frees the meshes and textures of the model
+func (m *Model) Delete() {
+	for _, part := range m.Parts {
+		part.Mesh.Delete()
+	}
+	for _, tx := range m.Textures() {
+		tx.Delete()
+	}
+	if m.white != nil {
+		m.white.Delete()
+	}
+}
+
+// newWhiteTexture returns a 1x1 white texture
+func newWhiteTexture() *Texture {
+	white := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	white.Set(0, 0, color.White)
+	return NewTextureFromImage(white)
+}
+
+// textureCache loads each texture file once
+type textureCache map[string]*Texture
+
+// load returns the texture for path, or nil if path is empty
+func (c textureCache) load(path string) (*Texture, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if tx, ok := c[path]; ok {
+		return tx, nil
+	}
+	tx, err := NewTexture(path)
+	if err != nil {
+		return nil, err
+	}
+	c[path] = tx
+	return tx, nil
+}
@@ -0,0 +1,445 @@
+package render
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// OBJ is a parsed Wavefront OBJ file. Faces are split into a mesh for each
+// run of faces in the same object and group with the same material.
+type OBJ struct {
+	Meshes []*OBJMesh
+	// Materials holds the materials of every mtllib, by name
+	Materials map[string]*Material
+	// Warnings are the material libraries and materials that could not be
+	// found. Meshes using a missing material get NewMaterial's defaults.
+	Warnings []Diagnostic
+}
+
+// OBJMesh is the triangles of one run of faces, laid out as ModelLayout.
+// Vertices are shared between faces wherever their position, normal and
+// texture coordinates all match. Missing normals and texture coordinates
+// are zero.
+type OBJMesh struct {
+	Object   string
+	Group    string
+	Material string
+	MeshData
+}
+
+// LoadOBJ reads an OBJ file with its materials, uploads its meshes and
+// loads the texture maps of its materials
+func LoadOBJ(path string) (*Model, error) {
+	obj, err := ReadOBJ(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range obj.Warnings {
+		log.Println(w.Format())
+	}
+	model := &Model{white: newWhiteTexture()}
+	textures := textureCache{}
+	for _, mesh := range obj.Meshes {
+		material, ok := obj.Materials[mesh.Material]
+		if !ok {
+			material = NewMaterial(mesh.Material)
+		}
+		name := mesh.Object
+		if name == "" {
+			name = mesh.Group
+		}
+		uploaded, err := mesh.Upload()
+		if err != nil {
+			model.Delete()
+			return nil, err
+		}
+		part := &ModelPart{
+			Name:     name,
+			Mesh:     uploaded,
+			Material: material,
+		}
+		model.Parts = append(model.Parts, part)
+		maps := []struct {
+			texture **Texture
+			path    string
+		}{
+			{&part.DiffuseTexture, material.DiffuseMap},
+			{&part.SpecularTexture, material.SpecularMap},
+			{&part.NormalTexture, material.NormalMap},
+		}
+		for _, m := range maps {
+			if *m.texture, err = textures.load(m.path); err != nil {
+				model.Delete()
+				return nil, err
+			}
+		}
+	}
+	return model, nil
+}
+
+// ReadOBJ parses an OBJ file and the MTL files it references
+func ReadOBJ(path string) (*OBJ, error) {
+	text, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseOBJ(path, text)
+}
+
+// ParseOBJ parses OBJ text held in memory. name is used in errors, and
+// mtllib files are read relative to its directory.
+//
+// Polygons are split into triangle fans, so they should be convex. Texture
+// coordinates are flipped vertically to match NewTexture, which uploads
+// images top row first. Statements other than v, vt, vn, f, o, g, usemtl
+// and mtllib are ignored.
+func ParseOBJ(name string, text string) (*OBJ, error) {
+	p := &objParser{obj: &OBJ{Materials: map[string]*Material{}}}
+	err := eachLine(name, text, func(diag Diagnostic, fields []string) error {
+		p.diag = diag
+		return p.parseLine(fields)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.obj, nil
+}
+
+type objParser struct {
+	obj  *OBJ
+	diag Diagnostic
+
+	positions []mgl32.Vec3
+	normals   []mgl32.Vec3
+	texCoords []mgl32.Vec2
+
+	object   string
+	group    string
+	material string
+	// mesh is the mesh faces are added to, nil until the first face after
+	// the object, group or material changes
+	mesh     *OBJMesh
+	vertices map[[3]int]uint32
+}
+
+func (p *objParser) parseLine(fields []string) error {
+	args := fields[1:]
+	switch fields[0] {
+	case "v":
+		if len(args) < 3 {
+			return p.errorf("expected x y z")
+		}
+		v, err := p.floats(args[:3])
+		if err != nil {
+			return err
+		}
+		p.positions = append(p.positions, mgl32.Vec3{v[0], v[1], v[2]})
+	case "vn":
+		if len(args) != 3 {
+			return p.errorf("expected x y z")
+		}
+		v, err := p.floats(args)
+		if err != nil {
+			return err
+		}
+		p.normals = append(p.normals, mgl32.Vec3{v[0], v[1], v[2]})
+	case "vt":
+		if len(args) < 1 || len(args) > 3 {
+			return p.errorf("expected u [v [w]]")
+		}
+		v, err := p.floats(args)
+		if err != nil {
+			return err
+		}
+		uv := mgl32.Vec2{v[0], 0}
+		if len(v) > 1 {
+			uv[1] = v[1]
+		}
+		p.texCoords = append(p.texCoords, uv)
+	case "f":
+		return p.face(args)
+	case "o":
+		p.object = strings.Join(args, " ")
+		p.mesh = nil
+	case "g":
+		p.group = strings.Join(args, " ")
+		p.mesh = nil
+	case "usemtl":
+		if len(args) != 1 {
+			return p.errorf("expected a material name")
+		}
+		if _, ok := p.obj.Materials[args[0]]; !ok {
+			p.warnf("unknown material %q, using the default material", args[0])
+		}
+		p.material = args[0]
+		p.mesh = nil
+	case "mtllib":
+		if len(args) == 0 {
+			return p.errorf("expected a file name")
+		}
+		for _, file := range args {
+			materials, err := ReadMTL(filepath.Join(filepath.Dir(p.diag.File), mapPath(file)))
+			if _, ok := err.(*FileNotFoundError); ok {
+				p.warnf("material library %s not found", file)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			for name, material := range materials {
+				p.obj.Materials[name] = material
+			}
+		}
+	}
+	return nil
+}
+
+// face adds a polygon to the current mesh as a fan of triangles
+func (p *objParser) face(args []string) error {
+	if len(args) < 3 {
+		return p.errorf("a face needs at least 3 vertices, got %d", len(args))
+	}
+	if p.mesh == nil {
+		p.mesh = &OBJMesh{
+			Object:   p.object,
+			Group:    p.group,
+			Material: p.material,
+			MeshData: MeshData{Layout: ModelLayout},
+		}
+		p.obj.Meshes = append(p.obj.Meshes, p.mesh)
+		p.vertices = map[[3]int]uint32{}
+	}
+	polygon := make([]uint32, len(args))
+	for i, arg := range args {
+		index, err := p.vertex(arg)
+		if err != nil {
+			return err
+		}
+		polygon[i] = index
+	}
+	for i := 1; i+1 < len(polygon); i++ {
+		p.mesh.Indices = append(p.mesh.Indices, polygon[0], polygon[i], polygon[i+1])
+	}
+	return nil
+}
+
+// vertex returns the index of the mesh vertex for a face vertex such as
+// 1/2/3, 1//3 or 1, adding the vertex if the mesh doesn't have it yet
+func (p *objParser) vertex(arg string) (uint32, error) {
+	refs := strings.Split(arg, "/")
+	if len(refs) > 3 {
+		return 0, p.errorf("bad face vertex %q", arg)
+	}
+	key := [3]int{-1, -1, -1}
+	counts := []int{len(p.positions), len(p.texCoords), len(p.normals)}
+	kinds := []string{"position", "texture coordinate", "normal"}
+	for i, ref := range refs {
+		if ref == "" && i > 0 {
+			continue
+		}
+		n, err := strconv.Atoi(ref)
+		if err != nil || n == 0 {
+			return 0, p.errorf("bad %s index %q in %q", kinds[i], ref, arg)
+		}
+		// negative indices count back from the last one defined
+		if n < 0 {
+			n += counts[i] + 1
+		}
+		if n < 1 || n > counts[i] {
+			return 0, p.errorf("%s index %s out of range, %d defined", kinds[i], ref, counts[i])
+		}
+		key[i] = n - 1
+	}
+	if index, ok := p.vertices[key]; ok {
+		return index, nil
+	}
+
+	position := p.positions[key[0]]
+	var normal mgl32.Vec3
+	var uv mgl32.Vec2
+	if key[1] >= 0 {
+		uv = p.texCoords[key[1]]
+		uv[1] = 1 - uv[1]
+	}
+	if key[2] >= 0 {
+		normal = p.normals[key[2]]
+	}
+	index := uint32(p.mesh.VertexCount())
+	p.mesh.Vertices = append(p.mesh.Vertices,
+		position[0], position[1], position[2],
+		normal[0], normal[1], normal[2],
+		uv[0], uv[1])
+	p.vertices[key] = index
+	return index, nil
+}
+
+func (p *objParser) floats(args []string) ([]float32, error) {
+	return parseFloats(p.diag, args)
+}
+
+func (p *objParser) errorf(format string, args ...interface{}) error {
+	return modelErrorf(p.diag, format, args...)
+}
+
+// warnf records a warning for the current line
+func (p *objParser) warnf(format string, args ...interface{}) {
+	diag := p.diag
+	diag.Severity = SeverityWarning
+	diag.Message = fmt.Sprintf(format, args...)
+	p.obj.Warnings = append(p.obj.Warnings, diag)
+}
+
+// ReadMTL parses an MTL file
+func ReadMTL(path string) (map[string]*Material, error) {
+	text, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMTL(path, text)
+}
+
+// ParseMTL parses MTL text held in memory, returning its materials by
+// name. name is used in errors, and texture maps are relative to its
+// directory. Map options such as -s are skipped; the file name is the last
+// argument.
+func ParseMTL(name string, text string) (map[string]*Material, error) {
+	materials := map[string]*Material{}
+	var material *Material
+	dir := filepath.Dir(name)
+	err := eachLine(name, text, func(diag Diagnostic, fields []string) error {
+		statement, args := fields[0], fields[1:]
+		if statement == "newmtl" {
+			if len(args) != 1 {
+				return modelErrorf(diag, "expected a material name")
+			}
+			material = NewMaterial(args[0])
+			materials[args[0]] = material
+			return nil
+		}
+		if material == nil {
+			return modelErrorf(diag, "%s before newmtl", statement)
+		}
+		switch statement {
+		case "Ka", "Kd", "Ks", "Ke":
+			color, err := parseColor(diag, args)
+			if err != nil {
+				return err
+			}
+			switch statement {
+			case "Ka":
+				material.Ambient = color
+			case "Kd":
+				material.Diffuse = color
+			case "Ks":
+				material.Specular = color
+			case "Ke":
+				material.Emissive = color
+			}
+		case "Ns", "d", "Tr":
+			if len(args) != 1 {
+				return modelErrorf(diag, "expected a single value")
+			}
+			v, err := parseFloats(diag, args)
+			if err != nil {
+				return err
+			}
+			switch statement {
+			case "Ns":
+				material.Shininess = v[0]
+			case "d":
+				material.Opacity = v[0]
+			case "Tr":
+				material.Opacity = 1 - v[0]
+			}
+		case "map_Ka", "map_Kd", "map_Ks", "map_Bump", "map_bump", "bump", "norm", "map_d":
+			if len(args) == 0 {
+				return modelErrorf(diag, "expected a file name")
+			}
+			path := filepath.Join(dir, mapPath(args[len(args)-1]))
+			switch statement {
+			case "map_Ka":
+				material.AmbientMap = path
+			case "map_Kd":
+				material.DiffuseMap = path
+			case "map_Ks":
+				material.SpecularMap = path
+			case "map_d":
+				material.OpacityMap = path
+			default:
+				material.NormalMap = path
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return materials, nil
+}
+
+// parseColor parses r g b, or a single value for gray
+func parseColor(diag Diagnostic, args []string) (mgl32.Vec3, error) {
+	if len(args) != 1 && len(args) != 3 {
+		return mgl32.Vec3{}, modelErrorf(diag, "expected r g b")
+	}
+	v, err := parseFloats(diag, args)
+	if err != nil {
+		return mgl32.Vec3{}, err
+	}
+	if len(v) == 1 {
+		return mgl32.Vec3{v[0], v[0], v[0]}, nil
+	}
+	return mgl32.Vec3{v[0], v[1], v[2]}, nil
+}
+
+// eachLine calls fn with the fields of each line of a model file, skipping
+// blank lines and # comments. Lines ending in \ continue on the next line.
+func eachLine(name string, text string, fn func(diag Diagnostic, fields []string) error) error {
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		diag := Diagnostic{Severity: SeverityError, File: name, Line: i + 1, Source: lines[i]}
+		line := strings.TrimRight(lines[i], "\r")
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + " " + strings.TrimRight(lines[i], "\r")
+		}
+		if c := strings.Index(line, "#"); c >= 0 {
+			line = line[:c]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := fn(diag, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseFloats(diag Diagnostic, args []string) ([]float32, error) {
+	v := make([]float32, len(args))
+	for i, arg := range args {
+		f, err := strconv.ParseFloat(arg, 32)
+		if err != nil {
+			return nil, modelErrorf(diag, "bad number %q", arg)
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
+}
+
+func modelErrorf(diag Diagnostic, format string, args ...interface{}) error {
+	diag.Message = fmt.Sprintf(format, args...)
+	return &ModelError{diag}
+}
+
+// mapPath converts a file name written on Windows to a local path
+func mapPath(file string) string {
+	return filepath.FromSlash(strings.Replace(file, "\\", "/", -1))
+}
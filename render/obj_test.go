@@ -0,0 +1,208 @@
+package render
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const quadOBJ = `# a unit quad
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vn 0 0 1
+o quad
+f 1/1/1 2/2/1 3/3/1 4/4/1
+`
+
+func TestParseOBJQuad(t *testing.T) {
+	obj, err := ParseOBJ("quad.obj", quadOBJ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obj.Meshes) != 1 {
+		t.Fatalf("got %d meshes, want 1", len(obj.Meshes))
+	}
+	mesh := obj.Meshes[0]
+	if mesh.Object != "quad" {
+		t.Errorf("object = %q, want quad", mesh.Object)
+	}
+	// the quad is split into a fan of two triangles sharing 4 vertices
+	if want := []uint32{0, 1, 2, 0, 2, 3}; !reflect.DeepEqual(mesh.Indices, want) {
+		t.Errorf("indices = %v, want %v", mesh.Indices, want)
+	}
+	if mesh.VertexCount() != 4 {
+		t.Fatalf("got %d vertices, want 4", mesh.VertexCount())
+	}
+	// position, normal, and texture coordinates flipped vertically
+	want := []float32{1, 1, 0, 0, 0, 1, 1, 0}
+	if got := mesh.Vertices[2*8 : 3*8]; !reflect.DeepEqual(got, want) {
+		t.Errorf("vertex 2 = %v, want %v", got, want)
+	}
+}
+
+func TestParseOBJSharesVertices(t *testing.T) {
+	obj, err := ParseOBJ("shared.obj", `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vt 1 1
+f 1/1 2/1 3/1
+f -4/1 -2/1 -1/1
+f 1/2 3/1 4/1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mesh := obj.Meshes[0]
+	// the second face reuses vertices 1 and 3 by negative index, the third
+	// face has a new texture coordinate for position 1
+	if want := []uint32{0, 1, 2, 0, 2, 3, 4, 2, 3}; !reflect.DeepEqual(mesh.Indices, want) {
+		t.Errorf("indices = %v, want %v", mesh.Indices, want)
+	}
+	if mesh.VertexCount() != 5 {
+		t.Errorf("got %d vertices, want 5", mesh.VertexCount())
+	}
+}
+
+func TestParseOBJMaterials(t *testing.T) {
+	dir := t.TempDir()
+	mtl := `newmtl red
+Kd 1 0 0
+Ns 32
+d 0.5
+map_Kd -s 2 2 1 textures\red.png
+
+newmtl plain
+Ka 0.2
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "scene.mtl"), []byte(mtl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := ParseOBJ(filepath.Join(dir, "scene.obj"), `mtllib scene.mtl
+v 0 0 0
+v 1 0 0
+v 0 1 0
+usemtl red
+f 1 2 3
+f 1 3 2
+usemtl plain
+f 1 2 3
+g other
+f 1 2 3
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var materials []string
+	for _, mesh := range obj.Meshes {
+		materials = append(materials, mesh.Material+"/"+mesh.Group)
+	}
+	if want := []string{"red/", "plain/", "plain/other"}; !reflect.DeepEqual(materials, want) {
+		t.Errorf("meshes = %v, want %v", materials, want)
+	}
+	if n := len(obj.Meshes[0].Indices); n != 6 {
+		t.Errorf("red mesh has %d indices, want 6", n)
+	}
+
+	red := obj.Materials["red"]
+	if red == nil {
+		t.Fatal("material red not parsed")
+	}
+	if red.Diffuse != (mgl32.Vec3{1, 0, 0}) || red.Shininess != 32 || red.Opacity != 0.5 {
+		t.Errorf("red = %+v", red)
+	}
+	if want := filepath.Join(dir, "textures", "red.png"); red.DiffuseMap != want {
+		t.Errorf("diffuse map = %q, want %q", red.DiffuseMap, want)
+	}
+	if plain := obj.Materials["plain"]; plain.Ambient != (mgl32.Vec3{0.2, 0.2, 0.2}) {
+		t.Errorf("plain ambient = %v", plain.Ambient)
+	}
+}
+
+func TestParseOBJErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		line int
+	}{
+		{"v 0 0\n", 1},
+		{"v 0 0 0\nv 0 x 0\n", 2},
+		{"v 0 0 0\nv 1 0 0\nf 1 2\n", 3},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 4\n", 4},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 0\n", 4},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1/1 2 3\n", 4},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 -4\n", 4},
+	}
+	for _, test := range tests {
+		_, err := ParseOBJ("bad.obj", test.text)
+		modelErr, ok := err.(*ModelError)
+		if !ok {
+			t.Errorf("%q: got %v, want a ModelError", test.text, err)
+			continue
+		}
+		if modelErr.File != "bad.obj" || modelErr.Line != test.line {
+			t.Errorf("%q: error at %s:%d, want bad.obj:%d", test.text, modelErr.File, modelErr.Line, test.line)
+		}
+	}
+}
+
+func TestParseOBJMissingMaterials(t *testing.T) {
+	obj, err := ParseOBJ("scene.obj", `# comment
+
+mtllib missing.mtl
+v 0 0 0
+v 1 0 0
+v 0 1 0
+usemtl red
+f 1 2 3
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obj.Meshes) != 1 || obj.Meshes[0].Material != "red" {
+		t.Errorf("meshes = %+v, want one using red", obj.Meshes)
+	}
+	var lines []int
+	for _, w := range obj.Warnings {
+		if w.Severity != SeverityWarning || w.File != "scene.obj" {
+			t.Errorf("warning = %+v", w)
+		}
+		lines = append(lines, w.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{3, 7}) {
+		t.Errorf("warnings on lines %v, want 3 and 7: %v", lines, obj.Warnings)
+	}
+}
+
+func TestParseMTLErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		line int
+	}{
+		{"Kd 1 1 1\n", 1},
+		{"newmtl a\nKd 1 1\n", 2},
+		{"newmtl a\n\nNs x\n", 3},
+		{"newmtl a\nmap_Kd\n", 2},
+	}
+	for _, test := range tests {
+		_, err := ParseMTL("bad.mtl", test.text)
+		modelErr, ok := err.(*ModelError)
+		if !ok {
+			t.Errorf("%q: got %v, want a ModelError", test.text, err)
+			continue
+		}
+		if modelErr.Line != test.line {
+			t.Errorf("%q: error on line %d, want %d", test.text, modelErr.Line, test.line)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package render
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Source is preprocessed shader source ready to compile
+type Source struct {
+	// Text is the expanded, null terminated source
+	Text string
+	// Files maps the source string numbers used in the #line directives,
+	// and so in driver info logs, to file names. Files[0] is the root file.
+	Files []string
+	// Includes maps each file to the files it includes directly
+	Includes map[string][]string
+	// Defaults are the uniform defaults declared in a stage file
+	Defaults []UniformDefault
+
+	// lines holds the original lines of each file, by source string number
+	lines [][]string
+	// once holds the files expanded so far that declare #pragma once
+	once map[int]bool
+	// locations maps each line of Text to the file line it came from
+	locations []location
+}
+
+type location struct {
+	file int
+	line int
+}
+
+// Deps returns every file the source was built from, starting with the root
+func (src *Source) Deps() []string {
+	return append([]string(nil), src.Files...)
+}
+
+// Line returns the 1 based line of the named file, or "" if it is unknown
+func (src *Source) Line(file string, line int) string {
+	for i, name := range src.Files {
+		if name == file && line >= 1 && line <= len(src.lines[i]) {
+			return src.lines[i][line-1]
+		}
+	}
+	return ""
+}
+
+// locate maps a 1 based line of Text back to its file and line, or returns
+// an empty file for lines added by the preprocessor
+func (src *Source) locate(line int) (string, int) {
+	if line < 1 || line > len(src.locations) || src.locations[line-1].line == 0 {
+		return "", 0
+	}
+	loc := src.locations[line-1]
+	return src.Files[loc.file], loc.line
+}
+
+// Preprocessor expands #include directives in shader files. Includes of
+// the form #include "file" are resolved relative to the including file
+// and then against SearchPaths; #include <file> only uses SearchPaths.
+// #line directives are emitted around each include so driver diagnostics
+// can be mapped back to the right file. A file declaring #pragma once is
+// expanded at most once per source, and directives inside comments are
+// left alone.
+type Preprocessor struct {
+	SearchPaths []string
+}
+
+// DefaultPreprocessor is used by the NewShader and NewShaders constructors
+var DefaultPreprocessor = &Preprocessor{}
+
+// PreprocessError is returned for a bad #include, such as a missing file or
+// an include cycle
+type PreprocessError struct {
+	Diagnostic
+}
+
+func (e *PreprocessError) Error() string {
+	return e.Format()
+}
+
+var includeDirective = regexp.MustCompile(`^\s*#\s*include\s+(?:"([^"]+)"|<([^>]+)>)\s*(?://.*)?$`)
+var versionDirective = regexp.MustCompile(`^\s*#\s*version\b`)
+var onceDirective = regexp.MustCompile(`^\s*#\s*pragma\s+once\s*$`)
+
+// Process reads and expands the shader file at path
+func (pp *Preprocessor) Process(path string) (*Source, error) {
+	text, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return pp.ProcessSource(path, text)
+}
+
+// ProcessSource expands a shader held in memory. name is used in
+// diagnostics and to resolve relative includes.
+func (pp *Preprocessor) ProcessSource(name string, text string) (*Source, error) {
+	src := &Source{
+		Includes: map[string][]string{},
+		once:     map[int]bool{},
+	}
+	var out []string
+	if err := pp.expand(src, &out, name, text, nil); err != nil {
+		return nil, err
+	}
+	src.Text = strings.Join(out, "\n") + "\x00"
+	return src, nil
+}
+
+// expand appends the lines of a file to out, recursing into includes.
+// stack holds the files currently being expanded, to detect cycles.
+func (pp *Preprocessor) expand(src *Source, out *[]string, name string, text string, stack []string) error {
+	index := src.fileIndex(name, text)
+	stack = append(stack, name)
+	lines := src.lines[index]
+
+	inComment := false
+	for i, line := range lines {
+		code := stripComments(line, &inComment)
+		if onceDirective.MatchString(code) {
+			src.once[index] = true
+			*out = append(*out, "")
+			src.locations = append(src.locations, location{file: index, line: i + 1})
+			continue
+		}
+		m := includeDirective.FindStringSubmatch(code)
+		if m == nil {
+			if len(stack) > 1 && versionDirective.MatchString(line) {
+				// only the root file may declare a version
+				line = ""
+			}
+			*out = append(*out, line)
+			src.locations = append(src.locations, location{file: index, line: i + 1})
+			continue
+		}
+
+		diag := Diagnostic{
+			Severity: SeverityError,
+			File:     name,
+			Line:     i + 1,
+			Source:   line,
+		}
+		target, quoted := m[1], true
+		if target == "" {
+			target, quoted = m[2], false
+		}
+		path, err := pp.resolve(name, target, quoted)
+		if err != nil {
+			diag.Message = err.Error()
+			return &PreprocessError{diag}
+		}
+		if included, ok := src.lookup(path); ok && src.once[included] {
+			src.Includes[name] = appendUnique(src.Includes[name], path)
+			*out = append(*out, "")
+			src.locations = append(src.locations, location{file: index, line: i + 1})
+			continue
+		}
+		for _, f := range stack {
+			if sameFile(f, path) {
+				diag.Message = fmt.Sprintf("include cycle: %s -> %s", strings.Join(stack, " -> "), path)
+				return &PreprocessError{diag}
+			}
+		}
+		included, err := readFile(path)
+		if err != nil {
+			diag.Message = err.Error()
+			return &PreprocessError{diag}
+		}
+		src.Includes[name] = appendUnique(src.Includes[name], path)
+
+		*out = append(*out, fmt.Sprintf("#line 1 %d", src.fileIndex(path, included)))
+		src.locations = append(src.locations, location{})
+		if err := pp.expand(src, out, path, included, stack); err != nil {
+			return err
+		}
+		*out = append(*out, fmt.Sprintf("#line %d %d", i+2, index))
+		src.locations = append(src.locations, location{})
+	}
+	return nil
+}
+
+// resolve finds an included file
+func (pp *Preprocessor) resolve(from string, target string, quoted bool) (string, error) {
+	var candidates []string
+	if quoted {
+		candidates = append(candidates, filepath.Join(filepath.Dir(from), target))
+	}
+	for _, dir := range pp.SearchPaths {
+		candidates = append(candidates, filepath.Join(dir, target))
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("could not find include %q", target)
+}
+
+// lookup returns the source string number of a file already in the source
+func (src *Source) lookup(name string) (int, bool) {
+	for i, f := range src.Files {
+		if sameFile(f, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// fileIndex returns the source string number of a file, adding it if needed
+func (src *Source) fileIndex(name string, text string) int {
+	if i, ok := src.lookup(name); ok {
+		return i
+	}
+	src.Files = append(src.Files, name)
+	src.lines = append(src.lines, strings.Split(strings.TrimRight(text, "\x00"), "\n"))
+	return len(src.Files) - 1
+}
+
+// sourceFromString wraps an unprocessed, null terminated source string
+func sourceFromString(name string, text string) *Source {
+	src := &Source{
+		Text:     text,
+		Includes: map[string][]string{},
+	}
+	src.fileIndex(name, text)
+	for i := range src.lines[0] {
+		src.locations = append(src.locations, location{file: 0, line: i + 1})
+	}
+	return src
+}
+
+// stripComments returns a line with its comments replaced by spaces.
+// inComment tracks whether a block comment is open across lines.
+func stripComments(line string, inComment *bool) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case *inComment:
+			if strings.HasPrefix(line[i:], "*/") {
+				*inComment = false
+				b.WriteByte(' ')
+				i++
+			}
+		case strings.HasPrefix(line[i:], "//"):
+			return b.String()
+		case strings.HasPrefix(line[i:], "/*"):
+			*inComment = true
+			i++
+		default:
+			b.WriteByte(line[i])
+		}
+	}
+	return b.String()
+}
+
+func readFile(path string) (string, error) {
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", &FileNotFoundError{Path: path}
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func sameFile(a string, b string) bool {
+	return absPath(a) == absPath(b)
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
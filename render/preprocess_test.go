@@ -0,0 +1,68 @@
+package render
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestProcessDiamondInclude(t *testing.T) {
+	// light.glsl and shadow.glsl both include common.glsl
+	dir := writeFiles(t, map[string]string{
+		"main.frag":   "#version 330 core\n#include \"light.glsl\"\n#include \"shadow.glsl\"\nvoid main() {}\n",
+		"light.glsl":  "#pragma once\n#include \"common.glsl\"\nfloat light() { return one(); }\n",
+		"shadow.glsl": "#include \"common.glsl\"\nfloat shadow() { return one(); }\n",
+		"common.glsl": "#pragma once\nfloat one() { return 1.0; }\n",
+	})
+	src, err := DefaultPreprocessor.Process(filepath.Join(dir, "main.frag"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(src.Text, "float one()"); n != 1 {
+		t.Errorf("common.glsl expanded %d times, want once:\n%s", n, src.Text)
+	}
+	if strings.Contains(src.Text, "pragma once") {
+		t.Errorf("#pragma once left in the source:\n%s", src.Text)
+	}
+	shadow := filepath.Join(dir, "shadow.glsl")
+	if deps := src.Includes[shadow]; len(deps) != 1 || filepath.Base(deps[0]) != "common.glsl" {
+		t.Errorf("includes of shadow.glsl = %v, want common.glsl", deps)
+	}
+	// diagnostics on the line after the skipped include map back to it
+	for i, line := range strings.Split(src.Text, "\n") {
+		if strings.HasPrefix(line, "float shadow()") {
+			if file, n := src.locate(i + 1); file != shadow || n != 2 {
+				t.Errorf("shadow() is at %s:%d, want %s:2", file, n, shadow)
+			}
+		}
+	}
+}
+
+func TestProcessIncludeInComment(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.frag": "#version 330 core\n/* disabled:\n#include \"missing.glsl\"\n*/\n// #include \"missing.glsl\"\n#include \"a.glsl\" /* used */\nvoid main() {}\n",
+		"a.glsl":    "float a() { return 1.0; }\n",
+	})
+	src, err := DefaultPreprocessor.Process(filepath.Join(dir, "main.frag"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(src.Text, "float a()") {
+		t.Errorf("a.glsl was not included:\n%s", src.Text)
+	}
+	if n := strings.Count(src.Text, `#include "missing.glsl"`); n != 2 {
+		t.Errorf("commented includes were changed:\n%s", src.Text)
+	}
+}
@@ -0,0 +1,80 @@
+package render
+
+// Program is a shader program that is rebuilt when its source files change.
+// A reload that fails to compile or link leaves the last good program in
+// place, so callers can keep drawing while the error is fixed. Uniform
+// values set through the Shader setters carry over to the rebuilt program.
+type Program struct {
+	*Shader
+	shaders *Shaders
+	err     error
+}
+
+// NewProgram links a program from the given shader files
+func NewProgram(shaders *Shaders) (*Program, error) {
+	program := &Program{
+		shaders: shaders,
+	}
+	if err := program.LoadShaders(); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+// LoadShaders builds the program from the current shader sources
+func (p *Program) LoadShaders() error {
+	sources, err := p.shaders.GetSource()
+	if err == nil {
+		err = p.build(sources)
+	}
+	p.err = err
+	return err
+}
+
+// UpdateShaders rebuilds the program if any of its shader files have changed.
+// The returned error is also kept until the next successful reload.
+func (p *Program) UpdateShaders() error {
+	updated, sources, err := p.shaders.GetUpdatedSource()
+	if err == nil && !updated {
+		return nil
+	}
+	if err == nil {
+		err = p.build(sources)
+	}
+	p.err = err
+	return err
+}
+
+// Paths returns the shader files the program is built from and the files
+// they include
+func (p *Program) Paths() []string {
+	return p.shaders.Deps()
+}
+
+// Reload rebuilds the program from its shader files, keeping the last good
+// program on failure
+func (p *Program) Reload() error {
+	return p.LoadShaders()
+}
+
+// Err returns the error from the last failed load or reload, or nil if the
+// program reflects the current shader sources
+func (p *Program) Err() error {
+	return p.err
+}
+
+// build compiles and links a candidate program and only replaces the
+// current one if that succeeds
+func (p *Program) build(sources map[Stage]*Source) error {
+	shader, err := NewShaderFromSources(sources)
+	if err != nil {
+		return err
+	}
+	old := p.Shader
+	p.Shader = shader
+	if old != nil {
+		shader.restoreUniforms(old)
+		old.Delete()
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Package render contains the gl helpers shared by the chapter programs:
+// shaders and programs, textures, vertex buffers and the glfw window.
+package render
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// Shader is a linked gl program built from one or more shader stages
+type Shader struct {
+	ID uint32
+	// Warnings holds link log warnings and mismatches between the outputs
+	// of one stage and the inputs of the next found while building the
+	// program
+	Warnings []Diagnostic
+
+	// uniforms caches the active uniforms by name
+	uniforms map[string]Uniform
+	// reported holds the names already logged by a uniform setter
+	reported map[string]bool
+	// values holds the last value set for each uniform
+	values map[string]uniformValue
+}
+
+// NewShader reads the fragment and vertex shader at the given paths,
+// expanding includes with DefaultPreprocessor, and links them into a program
+func NewShader(fragPath string, vertPath string) (*Shader, error) {
+	return NewShaderFromStages(map[Stage]string{
+		VertexStage:   vertPath,
+		FragmentStage: fragPath,
+	})
+}
+
+// NewShaderFromFiles links a program from shader files, telling their
+// stages apart by extension (see StageFromPath)
+func NewShaderFromFiles(paths ...string) (*Shader, error) {
+	stages, err := StagesFromPaths(paths...)
+	if err != nil {
+		return nil, err
+	}
+	return NewShaderFromStages(stages)
+}
+
+// NewShaderFromFile links a program from a .glsl stage file holding every
+// stage under #pragma stage sections
+func NewShaderFromFile(path string) (*Shader, error) {
+	sources, err := DefaultPreprocessor.ProcessStages(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewShaderFromSources(sources)
+}
+
+// NewShaderFromStages reads the shader file for each stage, expanding
+// includes with DefaultPreprocessor, and links them into a program
+func NewShaderFromStages(stages map[Stage]string) (*Shader, error) {
+	sources := map[Stage]*Source{}
+	for stage, path := range stages {
+		src, err := DefaultPreprocessor.Process(path)
+		if err != nil {
+			return nil, err
+		}
+		sources[stage] = src
+	}
+	return NewShaderFromSources(sources)
+}
+
+// NewShaderFromSource links a program from vertex and fragment source
+// strings. The sources must be null terminated.
+func NewShaderFromSource(vert string, frag string) (*Shader, error) {
+	return NewShaderFromSources(map[Stage]*Source{
+		VertexStage:   sourceFromString("<vertex>", vert),
+		FragmentStage: sourceFromString("<fragment>", frag),
+	})
+}
+
+// NewShaderFromSources links a program from preprocessed sources for each
+// stage
+func NewShaderFromSources(sources map[Stage]*Source) (*Shader, error) {
+	if err := checkStages(sources); err != nil {
+		return nil, err
+	}
+	shader := &Shader{
+		ID: gl.CreateProgram(),
+	}
+	if err := shader.attachShaders(sources); err != nil {
+		shader.Delete()
+		return nil, err
+	}
+	// check each stage's inputs against the previous stage's outputs
+	stages := sortedStages(sources)
+	for i := 1; i < len(stages); i++ {
+		from, to := stages[i-1], stages[i]
+		shader.Warnings = append(shader.Warnings, checkInterface(sources[from], from, sources[to], to)...)
+	}
+	shader.reflectUniforms()
+	shader.Warnings = append(shader.Warnings, shader.bindUniformBlocks()...)
+	gl.UseProgram(shader.ID)
+	shader.Warnings = append(shader.Warnings, shader.applyDefaults(stageDefaults(sources))...)
+	return shader, nil
+}
+
+// stageDefaults returns the uniform defaults of every stage, once each
+func stageDefaults(sources map[Stage]*Source) []UniformDefault {
+	var defaults []UniformDefault
+	seen := map[string]bool{}
+	for _, stage := range sortedStages(sources) {
+		for _, d := range sources[stage].Defaults {
+			key := fmt.Sprintf("%s:%d", d.File, d.Line)
+			if !seen[key] {
+				seen[key] = true
+				defaults = append(defaults, d)
+			}
+		}
+	}
+	return defaults
+}
+
+// Use makes the program current
+func (s *Shader) Use() {
+	gl.UseProgram(s.ID)
+}
+
+// PrintWarnings logs the warnings found while building the program
+func (s *Shader) PrintWarnings() {
+	for _, w := range s.Warnings {
+		log.Println(w.Format())
+	}
+}
+
+// Validate runs glValidateProgram against the current gl state, e.g. to
+// check that samplers of different types don't share a texture unit. It
+// should be called just before drawing.
+func (s *Shader) Validate() error {
+	gl.ValidateProgram(s.ID)
+	var status int32
+	gl.GetProgramiv(s.ID, gl.VALIDATE_STATUS, &status)
+	if status == gl.FALSE {
+		log := programInfoLog(s.ID)
+		return &ValidateError{
+			Log:         log,
+			Diagnostics: ParseInfoLog(log, nil),
+		}
+	}
+	return nil
+}
+
+// Dispatch runs a compute program over the given number of work groups
+func (s *Shader) Dispatch(x uint32, y uint32, z uint32) {
+	gl.UseProgram(s.ID)
+	gl.DispatchCompute(x, y, z)
+}
+
+// Delete frees the gl program
+func (s *Shader) Delete() {
+	gl.DeleteProgram(s.ID)
+}
+
+func (s *Shader) attachShaders(sources map[Stage]*Source) error {
+	for _, stage := range sortedStages(sources) {
+		shader, err := compileShader(sources[stage], stage)
+		if err != nil {
+			return err
+		}
+		// the shader is only flagged for deletion while it is attached
+		gl.AttachShader(s.ID, shader)
+		gl.DeleteShader(shader)
+	}
+	gl.LinkProgram(s.ID)
+
+	var success int32
+	gl.GetProgramiv(s.ID, gl.LINK_STATUS, &success)
+	log := programInfoLog(s.ID)
+	if success == gl.FALSE {
+		return &LinkError{
+			Log:         log,
+			Diagnostics: ParseInfoLog(log, nil),
+		}
+	}
+	// a successful link can still log warnings
+	for _, d := range ParseInfoLog(log, nil) {
+		d.Severity = SeverityWarning
+		s.Warnings = append(s.Warnings, d)
+	}
+	return nil
+}
+
+func programInfoLog(program uint32) string {
+	var logLength int32
+	gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
+	if logLength == 0 {
+		return ""
+	}
+	log := strings.Repeat("\x00", int(logLength+1))
+	gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
+	return strings.TrimRight(log, "\x00")
+}
+
+func compileShader(source *Source, stage Stage) (uint32, error) {
+	shader := gl.CreateShader(uint32(stage))
+	// compile shader
+	csources, free := gl.Strs(source.Text)
+	defer free()
+	gl.ShaderSource(shader, 1, csources, nil)
+	gl.CompileShader(shader)
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+
+	// check failure and log if necessary
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
+		gl.DeleteShader(shader)
+		return 0, newCompileError(source, stage, strings.TrimRight(log, "\x00"))
+	}
+	return shader, nil
+}
+
+func newCompileError(source *Source, stage Stage, log string) *CompileError {
+	diags := ParseInfoLog(log, source.Files)
+	for i := range diags {
+		diags[i].Source = source.Line(diags[i].File, diags[i].Line)
+	}
+	return &CompileError{
+		Path:        source.Files[0],
+		Stage:       stage,
+		Log:         log,
+		Diagnostics: diags,
+	}
+}
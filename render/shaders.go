@@ -0,0 +1,198 @@
+package render
+
+import (
+	"os"
+)
+
+// Shaders represents the shader files used by a reloadable program, one per
+// pipeline stage, along with the files they include
+type Shaders struct {
+	files map[Stage]*ShaderFile
+	// stageFile is set instead of files for a .glsl stage file
+	stageFile *ShaderFile
+
+	// Preprocessor expands includes; it defaults to DefaultPreprocessor
+	Preprocessor *Preprocessor
+
+	watcher *Watcher
+	deps    []string
+	changes chan Change
+}
+
+// NewShaders returns an object containing shaders from the paths listed
+func NewShaders(vertPath string, fragPath string) (*Shaders, error) {
+	return NewShadersFromStages(map[Stage]string{
+		VertexStage:   vertPath,
+		FragmentStage: fragPath,
+	})
+}
+
+// NewShadersFromFiles returns an object containing the shader files listed,
+// telling their stages apart by extension (see StageFromPath)
+func NewShadersFromFiles(paths ...string) (*Shaders, error) {
+	stages, err := StagesFromPaths(paths...)
+	if err != nil {
+		return nil, err
+	}
+	return NewShadersFromStages(stages)
+}
+
+// NewShadersFromFile returns an object containing a .glsl stage file
+// holding every stage under #pragma stage sections
+func NewShadersFromFile(path string) (*Shaders, error) {
+	file, err := NewShaderFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Shaders{
+		stageFile:    file,
+		Preprocessor: DefaultPreprocessor,
+		deps:         []string{path},
+		changes:      make(chan Change, 1),
+	}, nil
+}
+
+// NewShadersFromStages returns an object containing the shader file for
+// each stage
+func NewShadersFromStages(stages map[Stage]string) (*Shaders, error) {
+	ss := &Shaders{
+		files:        map[Stage]*ShaderFile{},
+		Preprocessor: DefaultPreprocessor,
+		changes:      make(chan Change, 1),
+	}
+	for stage, path := range stages {
+		file, err := NewShaderFile(path)
+		if err != nil {
+			return nil, err
+		}
+		ss.files[stage] = file
+	}
+	for _, stage := range ss.stages() {
+		ss.deps = append(ss.deps, ss.files[stage].Path)
+	}
+	return ss, nil
+}
+
+// ShaderFile is the path to a shader source file
+type ShaderFile struct {
+	Path string
+}
+
+// NewShaderFile returns a ShaderFile, checking that the file exists
+func NewShaderFile(path string) (*ShaderFile, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, &FileNotFoundError{Path: path}
+	} else if err != nil {
+		return nil, err
+	}
+	return &ShaderFile{
+		Path: path,
+	}, nil
+}
+
+// Watch subscribes the shaders and everything they include to changes from
+// w. Until Watch is called GetUpdatedSource never reports an update.
+func (ss *Shaders) Watch(w *Watcher) error {
+	ss.watcher = w
+	for i, path := range ss.deps {
+		if err := w.Watch(path, ss.changes); err != nil {
+			for _, watched := range ss.deps[:i] {
+				w.Unwatch(watched, ss.changes)
+			}
+			ss.watcher = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// Deps returns the shader files and every file they included when last read
+func (ss *Shaders) Deps() []string {
+	return append([]string(nil), ss.deps...)
+}
+
+// GetSource reads and preprocesses the shader for each stage. If the
+// shaders are being watched, files that are newly included start being
+// watched too.
+func (ss *Shaders) GetSource() (map[Stage]*Source, error) {
+	sources, err := ss.readSources()
+	if err != nil {
+		return nil, err
+	}
+	var deps []string
+	for _, stage := range sortedStages(sources) {
+		deps = append(deps, sources[stage].Deps()...)
+	}
+	ss.setDeps(deps)
+	return sources, nil
+}
+
+func (ss *Shaders) readSources() (map[Stage]*Source, error) {
+	if ss.stageFile != nil {
+		return ss.Preprocessor.ProcessStages(ss.stageFile.Path)
+	}
+	sources := map[Stage]*Source{}
+	for stage, file := range ss.files {
+		src, err := ss.Preprocessor.Process(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		sources[stage] = src
+	}
+	return sources, nil
+}
+
+// GetUpdatedSource returns the source for each stage if any of the shaders
+// or their includes has changed since the last call. Each change is
+// reported once.
+func (ss *Shaders) GetUpdatedSource() (updated bool, sources map[Stage]*Source, err error) {
+	select {
+	case <-ss.changes:
+		updated = true
+		sources, err = ss.GetSource()
+	default:
+	}
+	return
+}
+
+// stages returns the stages in pipeline order
+func (ss *Shaders) stages() []Stage {
+	var stages []Stage
+	for stage := range ss.files {
+		stages = append(stages, stage)
+	}
+	sortStages(stages)
+	return stages
+}
+
+// setDeps replaces the dependency list, updating the watched files
+func (ss *Shaders) setDeps(deps []string) {
+	var unique []string
+	for _, dep := range deps {
+		unique = appendUnique(unique, dep)
+	}
+	if ss.watcher != nil {
+		for _, old := range ss.deps {
+			if !containsFile(unique, old) {
+				ss.watcher.Unwatch(old, ss.changes)
+			}
+		}
+		for _, dep := range unique {
+			if !containsFile(ss.deps, dep) {
+				// the file was just read, so this only fails if it was
+				// removed in the meantime
+				ss.watcher.Watch(dep, ss.changes)
+			}
+		}
+	}
+	ss.deps = unique
+}
+
+func containsFile(files []string, file string) bool {
+	for _, f := range files {
+		if sameFile(f, file) {
+			return true
+		}
+	}
+	return false
+}
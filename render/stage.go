@@ -0,0 +1,149 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// Stage is a programmable pipeline stage. Its value is the gl shader type.
+type Stage uint32
+
+const (
+	VertexStage         Stage = gl.VERTEX_SHADER
+	TessControlStage    Stage = gl.TESS_CONTROL_SHADER
+	TessEvaluationStage Stage = gl.TESS_EVALUATION_SHADER
+	GeometryStage       Stage = gl.GEOMETRY_SHADER
+	FragmentStage       Stage = gl.FRAGMENT_SHADER
+	// ComputeStage needs gl 4.3 or ARB_compute_shader, and can't be linked
+	// with the other stages
+	ComputeStage Stage = gl.COMPUTE_SHADER
+)
+
+// pipelineOrder lists the graphics stages in the order data flows through them
+var pipelineOrder = []Stage{
+	VertexStage,
+	TessControlStage,
+	TessEvaluationStage,
+	GeometryStage,
+	FragmentStage,
+}
+
+var stageNames = map[Stage]string{
+	VertexStage:         "vertex",
+	TessControlStage:    "tessellation control",
+	TessEvaluationStage: "tessellation evaluation",
+	GeometryStage:       "geometry",
+	FragmentStage:       "fragment",
+	ComputeStage:        "compute",
+}
+
+var stageExtensions = map[string]Stage{
+	".vert": VertexStage,
+	".tesc": TessControlStage,
+	".tese": TessEvaluationStage,
+	".geom": GeometryStage,
+	".frag": FragmentStage,
+	".comp": ComputeStage,
+}
+
+func (s Stage) String() string {
+	if name, ok := stageNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("stage(0x%x)", uint32(s))
+}
+
+// StageFromPath returns the stage of a shader file from its extension:
+// .vert, .tesc, .tese, .geom, .frag or .comp
+func StageFromPath(path string) (Stage, error) {
+	if stage, ok := stageExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return stage, nil
+	}
+	return 0, fmt.Errorf("can't tell the shader stage of %s from its extension", path)
+}
+
+// StagesFromPaths maps shader files to stages by extension
+func StagesFromPaths(paths ...string) (map[Stage]string, error) {
+	stages := map[Stage]string{}
+	for _, path := range paths {
+		stage, err := StageFromPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := stages[stage]; ok {
+			return nil, fmt.Errorf("%s and %s are both %s shaders", other, path, stage)
+		}
+		stages[stage] = path
+	}
+	return stages, nil
+}
+
+// sortedStages returns the stages present in pipeline order, compute last
+func sortedStages(sources map[Stage]*Source) []Stage {
+	var stages []Stage
+	for stage := range sources {
+		stages = append(stages, stage)
+	}
+	sortStages(stages)
+	return stages
+}
+
+func sortStages(stages []Stage) {
+	sort.Slice(stages, func(i, j int) bool {
+		return stageIndex(stages[i]) < stageIndex(stages[j])
+	})
+}
+
+func stageIndex(s Stage) int {
+	for i, stage := range pipelineOrder {
+		if stage == s {
+			return i
+		}
+	}
+	return len(pipelineOrder)
+}
+
+// checkStages reports combinations of stages that can't be linked
+func checkStages(stages map[Stage]*Source) error {
+	if len(stages) == 0 {
+		return errors.New("a shader program needs at least one stage")
+	}
+	if _, ok := stages[ComputeStage]; ok {
+		if len(stages) > 1 {
+			return errors.New("a compute shader can't be linked with other stages")
+		}
+		if !computeSupported() {
+			return errors.New("compute shaders need gl 4.3 or ARB_compute_shader")
+		}
+	}
+	for stage := range stages {
+		if _, ok := stageNames[stage]; !ok {
+			return fmt.Errorf("unknown shader %s", stage)
+		}
+	}
+	return nil
+}
+
+// computeSupported reports whether the current context can run compute
+// shaders
+func computeSupported() bool {
+	var major, minor int32
+	gl.GetIntegerv(gl.MAJOR_VERSION, &major)
+	gl.GetIntegerv(gl.MINOR_VERSION, &minor)
+	if major > 4 || major == 4 && minor >= 3 {
+		return true
+	}
+	var count int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &count)
+	for i := int32(0); i < count; i++ {
+		if gl.GoStr(gl.GetStringi(gl.EXTENSIONS, uint32(i))) == "GL_ARB_compute_shader" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// A .glsl stage file holds every stage of a program in one file, e.g.
+//
+//	#version 330 core
+//	#pragma default mixValue 0.2
+//
+//	#pragma stage vertex
+//	layout (location = 0) in vec3 aPos;
+//	void main() { gl_Position = vec4(aPos, 1.0); }
+//
+//	#pragma stage fragment
+//	uniform float mixValue;
+//	out vec4 FragColor;
+//	void main() { FragColor = vec4(mixValue); }
+//
+// Lines before the first stage pragma are shared by every stage. Each stage
+// is compiled from the whole file with the other stages' lines blanked, so
+// diagnostics report the file's real line numbers.
+var (
+	stagePragma   = regexp.MustCompile(`^\s*#\s*pragma\s+stage\b\s*(.*?)\s*(?://.*)?$`)
+	defaultPragma = regexp.MustCompile(`^\s*#\s*pragma\s+default\b\s*(.*?)\s*(?://.*)?$`)
+)
+
+var stagePragmaNames = map[string]Stage{
+	"vertex":          VertexStage,
+	"tess_control":    TessControlStage,
+	"tess_evaluation": TessEvaluationStage,
+	"geometry":        GeometryStage,
+	"fragment":        FragmentStage,
+	"compute":         ComputeStage,
+}
+
+// UniformDefault is a default uniform value declared in a stage file with
+// #pragma default name value..., set when the program is linked
+type UniformDefault struct {
+	Name   string
+	Values []float32
+	File   string
+	Line   int
+	Source string
+}
+
+// ProcessStages reads a .glsl stage file and returns the expanded source of
+// each stage it declares
+func (pp *Preprocessor) ProcessStages(path string) (map[Stage]*Source, error) {
+	text, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return pp.ProcessStagesSource(path, text)
+}
+
+// ProcessStagesSource splits a stage file held in memory. name is used in
+// diagnostics and to resolve relative includes.
+func (pp *Preprocessor) ProcessStagesSource(name string, text string) (map[Stage]*Source, error) {
+	lines := strings.Split(strings.TrimRight(text, "\x00"), "\n")
+	// the stage each line belongs to, 0 for shared lines
+	owners := make([]Stage, len(lines))
+	var defaults []UniformDefault
+	var current Stage
+	seen := map[Stage]bool{}
+	for i, line := range lines {
+		diag := Diagnostic{
+			Severity: SeverityError,
+			File:     name,
+			Line:     i + 1,
+			Source:   line,
+		}
+		if m := stagePragma.FindStringSubmatch(line); m != nil {
+			stage, ok := stagePragmaNames[m[1]]
+			switch {
+			case !ok:
+				diag.Message = fmt.Sprintf("unknown stage %q in #pragma stage", m[1])
+				return nil, &PreprocessError{diag}
+			case seen[stage]:
+				diag.Message = fmt.Sprintf("%s stage declared twice", stage)
+				return nil, &PreprocessError{diag}
+			}
+			seen[stage] = true
+			current = stage
+			lines[i] = ""
+			continue
+		}
+		if m := defaultPragma.FindStringSubmatch(line); m != nil {
+			d, err := parseDefault(m[1])
+			if err != nil {
+				diag.Message = err.Error()
+				return nil, &PreprocessError{diag}
+			}
+			for _, prev := range defaults {
+				if prev.Name == d.Name {
+					diag.Message = fmt.Sprintf("default for uniform %s already declared on line %d", d.Name, prev.Line)
+					return nil, &PreprocessError{diag}
+				}
+			}
+			d.File, d.Line, d.Source = name, i+1, line
+			defaults = append(defaults, d)
+			lines[i] = ""
+			continue
+		}
+		owners[i] = current
+	}
+	if len(seen) == 0 {
+		return nil, &PreprocessError{Diagnostic{
+			Severity: SeverityError,
+			File:     name,
+			Message:  "no #pragma stage sections",
+		}}
+	}
+
+	sources := map[Stage]*Source{}
+	for stage := range seen {
+		stageLines := make([]string, len(lines))
+		for i, line := range lines {
+			if owners[i] == 0 || owners[i] == stage {
+				stageLines[i] = line
+			}
+		}
+		src, err := pp.ProcessSource(name, strings.Join(stageLines, "\n"))
+		if err != nil {
+			return nil, err
+		}
+		src.Defaults = defaults
+		sources[stage] = src
+	}
+	return sources, nil
+}
+
+// parseDefault parses the arguments of #pragma default, e.g. "tint 1 0.5 0"
+func parseDefault(args string) (UniformDefault, error) {
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		return UniformDefault{}, fmt.Errorf("#pragma default needs a uniform name and a value")
+	}
+	d := UniformDefault{Name: fields[0]}
+	for _, field := range fields[1:] {
+		v, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			return UniformDefault{}, fmt.Errorf("bad value %q for uniform %s", field, d.Name)
+		}
+		d.Values = append(d.Values, float32(v))
+	}
+	return d, nil
+}
+
+// applyDefaults sets each default on the current program, returning
+// warnings for defaults that don't match an active uniform
+func (s *Shader) applyDefaults(defaults []UniformDefault) []Diagnostic {
+	var diags []Diagnostic
+	for _, d := range defaults {
+		var msg string
+		u, ok := s.uniforms[d.Name]
+		t := uniformTypes[u.Type]
+		switch {
+		case !ok:
+			msg = fmt.Sprintf("default for unknown or unused uniform '%s'", d.Name)
+		case t.kind == matrixKind || t.kind == doubleMatrixKind || t.components == 0:
+			msg = fmt.Sprintf("can't set a default for uniform '%s' of type %s", d.Name, typeName(u.Type))
+		case t.components != len(d.Values):
+			msg = fmt.Sprintf("default for %s uniform '%s' has %d values, want %d", t.name, d.Name, len(d.Values), t.components)
+		}
+		if msg != "" {
+			diags = append(diags, Diagnostic{
+				Severity: SeverityWarning,
+				File:     d.File,
+				Line:     d.Line,
+				Message:  msg,
+				Source:   d.Source,
+			})
+			continue
+		}
+		switch t.kind {
+		case floatKind:
+			setUniformfv(u.Location, d.Values)
+		case doubleKind:
+			doubles := make([]float64, len(d.Values))
+			for i, v := range d.Values {
+				doubles[i] = float64(v)
+			}
+			setUniformdv(u.Location, doubles)
+		case uintKind:
+			uints := make([]uint32, len(d.Values))
+			for i, v := range d.Values {
+				uints[i] = uint32(v)
+			}
+			setUniformuiv(u.Location, uints)
+		default:
+			ints := make([]int32, len(d.Values))
+			for i, v := range d.Values {
+				ints[i] = int32(v)
+			}
+			setUniformiv(u.Location, ints)
+		}
+	}
+	return diags
+}
+
+func setUniformfv(location int32, v []float32) {
+	switch len(v) {
+	case 1:
+		gl.Uniform1fv(location, 1, &v[0])
+	case 2:
+		gl.Uniform2fv(location, 1, &v[0])
+	case 3:
+		gl.Uniform3fv(location, 1, &v[0])
+	case 4:
+		gl.Uniform4fv(location, 1, &v[0])
+	}
+}
+
+func setUniformiv(location int32, v []int32) {
+	switch len(v) {
+	case 1:
+		gl.Uniform1iv(location, 1, &v[0])
+	case 2:
+		gl.Uniform2iv(location, 1, &v[0])
+	case 3:
+		gl.Uniform3iv(location, 1, &v[0])
+	case 4:
+		gl.Uniform4iv(location, 1, &v[0])
+	}
+}
+
+func setUniformuiv(location int32, v []uint32) {
+	switch len(v) {
+	case 1:
+		gl.Uniform1uiv(location, 1, &v[0])
+	case 2:
+		gl.Uniform2uiv(location, 1, &v[0])
+	case 3:
+		gl.Uniform3uiv(location, 1, &v[0])
+	case 4:
+		gl.Uniform4uiv(location, 1, &v[0])
+	}
+}
+
+func setUniformdv(location int32, v []float64) {
+	switch len(v) {
+	case 1:
+		gl.Uniform1dv(location, 1, &v[0])
+	case 2:
+		gl.Uniform2dv(location, 1, &v[0])
+	case 3:
+		gl.Uniform3dv(location, 1, &v[0])
+	case 4:
+		gl.Uniform4dv(location, 1, &v[0])
+	}
+}
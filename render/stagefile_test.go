@@ -0,0 +1,102 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const stageFile = `#version 330 core
+#pragma default tint 1 0.5 0
+const float scale = 2.0;
+
+#pragma stage vertex
+layout (location = 0) in vec3 aPos;
+void main() { gl_Position = vec4(aPos * scale, 1.0); }
+
+#pragma stage fragment
+uniform vec3 tint;
+out vec4 FragColor;
+void main() { FragColor = vec4(tint, 1.0); }
+`
+
+func TestProcessStages(t *testing.T) {
+	sources, err := DefaultPreprocessor.ProcessStagesSource("shader.glsl", stageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 2 || sources[VertexStage] == nil || sources[FragmentStage] == nil {
+		t.Fatalf("got stages %v, want vertex and fragment", sources)
+	}
+	file := strings.Split(stageFile, "\n")
+	for stage, own := range map[Stage]string{VertexStage: "gl_Position", FragmentStage: "FragColor"} {
+		src := sources[stage]
+		lines := strings.Split(strings.TrimRight(src.Text, "\x00"), "\n")
+		if len(lines) != len(file) {
+			t.Errorf("%s: got %d lines, want %d", stage, len(lines), len(file))
+			continue
+		}
+		for i, line := range lines {
+			// every line stays on its line of the file, so diagnostics
+			// need no mapping
+			if f, n := src.locate(i + 1); f != "shader.glsl" || n != i+1 {
+				t.Errorf("%s: line %d maps to %s:%d", stage, i+1, f, n)
+			}
+			switch {
+			case strings.Contains(file[i], "#pragma"):
+				if line != "" {
+					t.Errorf("%s: pragma left on line %d: %q", stage, i+1, line)
+				}
+			case i < 4:
+				if line != file[i] {
+					t.Errorf("%s: shared line %d is %q, want %q", stage, i+1, line, file[i])
+				}
+			case line != "" && line != file[i]:
+				t.Errorf("%s: line %d is %q, want %q", stage, i+1, line, file[i])
+			}
+		}
+		if !strings.Contains(src.Text, own) {
+			t.Errorf("%s: own lines missing:\n%s", stage, src.Text)
+		}
+		for other, text := range map[Stage]string{VertexStage: "gl_Position", FragmentStage: "FragColor"} {
+			if other != stage && strings.Contains(src.Text, text) {
+				t.Errorf("%s: lines of the %s stage not blanked:\n%s", stage, other, src.Text)
+			}
+		}
+		want := []UniformDefault{{
+			Name:   "tint",
+			Values: []float32{1, 0.5, 0},
+			File:   "shader.glsl",
+			Line:   2,
+			Source: "#pragma default tint 1 0.5 0",
+		}}
+		if !reflect.DeepEqual(src.Defaults, want) {
+			t.Errorf("%s: defaults = %+v, want %+v", stage, src.Defaults, want)
+		}
+	}
+}
+
+func TestProcessStagesErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		line int
+	}{
+		{"#version 330 core\nvoid main() {}\n", 0},
+		{"#pragma stage vertex\n#pragma stage pixel\n", 2},
+		{"#pragma stage vertex\n\n#pragma stage vertex\n", 3},
+		{"#pragma default tint\n#pragma stage vertex\n", 1},
+		{"#pragma default tint 1 red 0\n#pragma stage vertex\n", 1},
+		{"#pragma default scale 1\n#pragma stage vertex\n#pragma default scale 2\n", 3},
+	}
+	for _, test := range tests {
+		_, err := DefaultPreprocessor.ProcessStagesSource("bad.glsl", test.text)
+		ppErr, ok := err.(*PreprocessError)
+		if !ok {
+			t.Errorf("%q: got %v, want a PreprocessError", test.text, err)
+			continue
+		}
+		if ppErr.File != "bad.glsl" || ppErr.Line != test.line {
+			t.Errorf("%q: error at %s:%d, want bad.glsl:%d", test.text, ppErr.File, ppErr.Line, test.line)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// Texture is a 2D gl texture loaded from an image file
+type Texture struct {
+	ID   uint32
+	Path string
+}
+
+// NewTexture decodes the image at path and uploads it to a new gl texture
+// bound to the active texture unit
+func NewTexture(path string) (*Texture, error) {
+	rgba, err := loadImage(path)
+	if err != nil {
+		return nil, err
+	}
+	return newTexture(rgba, path), nil
+}
+
+// NewTextureFromImage uploads an image held in memory, e.g. one embedded in
+// a model file, to a new gl texture bound to the active texture unit. The
+// texture has no file, so it is never reloaded.
+func NewTextureFromImage(img image.Image) *Texture {
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return newTexture(rgba, "")
+}
+
+func newTexture(rgba *image.RGBA, path string) *Texture {
+	var id uint32
+	gl.GenTextures(1, &id)
+	gl.BindTexture(gl.TEXTURE_2D, id)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	uploadImage(rgba)
+
+	return &Texture{
+		ID:   id,
+		Path: path,
+	}
+}
+
+// Bind binds the texture to the given texture unit, e.g. gl.TEXTURE0
+func (t *Texture) Bind(unit uint32) {
+	gl.ActiveTexture(unit)
+	gl.BindTexture(gl.TEXTURE_2D, t.ID)
+}
+
+// Paths returns the image file the texture was loaded from, if any
+func (t *Texture) Paths() []string {
+	if t.Path == "" {
+		return nil
+	}
+	return []string{t.Path}
+}
+
+// Reload decodes the image file again and uploads it into the existing gl
+// texture, so the texture ID and any units it is bound to stay valid. If
+// the image can not be loaded the old contents are kept.
+func (t *Texture) Reload() error {
+	if t.Path == "" {
+		return nil
+	}
+	rgba, err := loadImage(t.Path)
+	if err != nil {
+		return err
+	}
+	// restore the active unit's binding afterwards
+	var bound int32
+	gl.GetIntegerv(gl.TEXTURE_BINDING_2D, &bound)
+	gl.BindTexture(gl.TEXTURE_2D, t.ID)
+	uploadImage(rgba)
+	gl.BindTexture(gl.TEXTURE_2D, uint32(bound))
+	return nil
+}
+
+// Delete frees the gl texture
+func (t *Texture) Delete() {
+	gl.DeleteTextures(1, &t.ID)
+}
+
+func loadImage(path string) (*image.RGBA, error) {
+	imgFile, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, &FileNotFoundError{Path: path}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer imgFile.Close()
+
+	img, _, err := image.Decode(imgFile)
+	if err != nil {
+		return nil, &UnsupportedImageError{Path: path, Err: err}
+	}
+
+	rgba := image.NewRGBA(img.Bounds())
+	if rgba.Stride != rgba.Rect.Size().X*4 {
+		return nil, &UnsupportedImageError{Path: path, Err: errors.New("unsupported image stride")}
+	}
+
+	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0, 0}, draw.Src)
+	return rgba, nil
+}
+
+// uploadImage replaces the contents of the bound texture and its mipmaps
+func uploadImage(rgba *image.RGBA) {
+	gl.TexImage2D(
+		gl.TEXTURE_2D,
+		0,
+		gl.RGBA,
+		int32(rgba.Rect.Size().X),
+		int32(rgba.Rect.Size().Y),
+		0,
+		gl.RGBA,
+		gl.UNSIGNED_BYTE,
+		gl.Ptr(rgba.Pix))
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+}
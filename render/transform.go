@@ -0,0 +1,89 @@
+package render
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Transform places an object with a translation, rotation and scale,
+// relative to its parent if it has one
+type Transform struct {
+	Position mgl32.Vec3
+	Rotation mgl32.Quat
+	Scale    mgl32.Vec3
+
+	parent   *Transform
+	children []*Transform
+}
+
+// NewTransform returns an identity transform
+func NewTransform() *Transform {
+	return &Transform{
+		Rotation: mgl32.QuatIdent(),
+		Scale:    mgl32.Vec3{1, 1, 1},
+	}
+}
+
+// Translate moves the transform by v
+func (t *Transform) Translate(v mgl32.Vec3) {
+	t.Position = t.Position.Add(v)
+}
+
+// Rotate rotates the transform by angle radians around axis
+func (t *Transform) Rotate(angle float32, axis mgl32.Vec3) {
+	t.Rotation = mgl32.QuatRotate(angle, axis.Normalize()).Mul(t.Rotation)
+}
+
+// Local returns the model matrix relative to the parent: scale, then
+// rotate, then translate
+func (t *Transform) Local() mgl32.Mat4 {
+	translate := mgl32.Translate3D(t.Position.X(), t.Position.Y(), t.Position.Z())
+	scale := mgl32.Scale3D(t.Scale.X(), t.Scale.Y(), t.Scale.Z())
+	return translate.Mul4(t.Rotation.Mat4()).Mul4(scale)
+}
+
+// World returns the model matrix including every parent's transform
+func (t *Transform) World() mgl32.Mat4 {
+	if t.parent == nil {
+		return t.Local()
+	}
+	return t.parent.World().Mul4(t.Local())
+}
+
+// Parent returns the parent transform, or nil
+func (t *Transform) Parent() *Transform {
+	return t.parent
+}
+
+// Children returns the transforms parented to t
+func (t *Transform) Children() []*Transform {
+	return append([]*Transform(nil), t.children...)
+}
+
+// AddChild parents child to t, detaching it from its previous parent
+func (t *Transform) AddChild(child *Transform) {
+	child.SetParent(t)
+}
+
+// SetParent parents t to parent, or detaches it if parent is nil. A parent
+// that is t itself or one of its descendants is ignored, as it would make
+// a cycle.
+func (t *Transform) SetParent(parent *Transform) {
+	for p := parent; p != nil; p = p.parent {
+		if p == t {
+			return
+		}
+	}
+	if t.parent != nil {
+		siblings := t.parent.children
+		for i, c := range siblings {
+			if c == t {
+				t.parent.children = append(siblings[:i], siblings[i+1:]...)
+				break
+			}
+		}
+	}
+	t.parent = parent
+	if parent != nil {
+		parent.children = append(parent.children, t)
+	}
+}
@@ -0,0 +1,407 @@
+package render
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// UniformBuffer is a uniform buffer object holding a Go struct in std140
+// layout. Fields are matched to block members by name, or by a
+// `std140:"name"` tag; `std140:"-"` skips a field. Supported field types
+// are float32, int32, uint32, bool, the mgl32 vector and Mat3/Mat4 types,
+// structs of those and arrays of any of them.
+//
+// Each block name gets a binding point shared by every program: programs
+// linked after the buffer is created are bound to it automatically, and
+// earlier ones are bound with Attach. The binding point is freed for other
+// blocks once every buffer of the name is deleted. Blocks should be
+// declared layout(std140) so their offsets match.
+type UniformBuffer struct {
+	ID      uint32
+	Name    string
+	Binding uint32
+
+	typ     reflect.Type
+	members []blockMember
+	data    []byte
+}
+
+// blockMember is a block member as reported by gl: arrays of vectors and
+// matrices are a single member named name[0]
+type blockMember struct {
+	name   string
+	offset int
+	// stride is the array stride, 0 for non-arrays
+	stride int
+}
+
+// uniformBuffers holds the buffer for each block name, and bindings the
+// binding point given to each name
+var (
+	uniformBuffers = map[string]*UniformBuffer{}
+	bindings       = map[string]*bindingPoint{}
+)
+
+// bindingPoint is a uniform buffer binding point, held by the buffers of
+// a block name
+type bindingPoint struct {
+	index   uint32
+	buffers int
+}
+
+// bind returns the binding point of a block name, giving it the lowest free
+// one if it has none
+func bind(name string) (uint32, error) {
+	if b, ok := bindings[name]; ok {
+		b.buffers++
+		return b.index, nil
+	}
+	var max int32
+	gl.GetIntegerv(gl.MAX_UNIFORM_BUFFER_BINDINGS, &max)
+	used := map[uint32]bool{}
+	for _, b := range bindings {
+		used[b.index] = true
+	}
+	for index := uint32(0); index < uint32(max); index++ {
+		if !used[index] {
+			bindings[name] = &bindingPoint{index: index, buffers: 1}
+			return index, nil
+		}
+	}
+	return 0, &BlockLayoutError{Block: name,
+		Message: fmt.Sprintf("all %d uniform buffer binding points are in use", max)}
+}
+
+// unbind releases a buffer's hold on the binding point of its block name
+func unbind(name string) {
+	b, ok := bindings[name]
+	if !ok {
+		return
+	}
+	b.buffers--
+	if b.buffers == 0 {
+		delete(bindings, name)
+	}
+}
+
+var (
+	vec2Type = reflect.TypeOf(mgl32.Vec2{})
+	vec3Type = reflect.TypeOf(mgl32.Vec3{})
+	vec4Type = reflect.TypeOf(mgl32.Vec4{})
+	mat3Type = reflect.TypeOf(mgl32.Mat3{})
+	mat4Type = reflect.TypeOf(mgl32.Mat4{})
+)
+
+// NewUniformBuffer creates a buffer for the named uniform block, laid out
+// from the struct v and filled with its value
+func NewUniformBuffer(name string, v interface{}) (*UniformBuffer, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("uniform block %s: want a struct, got %T", name, v)
+	}
+	size, _, err := std140Layout(value.Type())
+	if err != nil {
+		return nil, fmt.Errorf("uniform block %s: %v", name, err)
+	}
+	binding, err := bind(name)
+	if err != nil {
+		return nil, err
+	}
+	ub := &UniformBuffer{
+		Name:    name,
+		Binding: binding,
+		typ:     value.Type(),
+		members: flattenMembers(value.Type(), "", 0),
+		data:    make([]byte, size),
+	}
+	gl.GenBuffers(1, &ub.ID)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ub.ID)
+	gl.BufferData(gl.UNIFORM_BUFFER, size, nil, gl.DYNAMIC_DRAW)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, binding, ub.ID)
+	uniformBuffers[name] = ub
+	if err := ub.Update(v); err != nil {
+		ub.Delete()
+		return nil, err
+	}
+	return ub, nil
+}
+
+// Update uploads v, which must be of the type the buffer was created with,
+// in a single buffer update
+func (ub *UniformBuffer) Update(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if !value.IsValid() {
+		return fmt.Errorf("uniform block %s holds %v, got nil", ub.Name, ub.typ)
+	}
+	if value.Type() != ub.typ {
+		return fmt.Errorf("uniform block %s holds %v, got %T", ub.Name, ub.typ, v)
+	}
+	encodeStd140(ub.data, 0, value)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ub.ID)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, len(ub.data), gl.Ptr(ub.data))
+	return nil
+}
+
+// Attach checks the buffer's layout against the program's block of the
+// same name and binds the block to the buffer's binding point
+func (ub *UniformBuffer) Attach(s *Shader) error {
+	index := gl.GetUniformBlockIndex(s.ID, gl.Str(ub.Name+"\x00"))
+	if index == gl.INVALID_INDEX {
+		return &BlockLayoutError{Block: ub.Name, Message: "no active uniform block with that name"}
+	}
+	if err := ub.verify(s.ID, index); err != nil {
+		return err
+	}
+	gl.UniformBlockBinding(s.ID, index, ub.Binding)
+	return nil
+}
+
+// Delete frees the buffer, and the binding point of its block name if no
+// other buffer of the name is left
+func (ub *UniformBuffer) Delete() {
+	if ub.ID == 0 {
+		return
+	}
+	gl.DeleteBuffers(1, &ub.ID)
+	ub.ID = 0
+	unbind(ub.Name)
+	if uniformBuffers[ub.Name] == ub {
+		delete(uniformBuffers, ub.Name)
+	}
+}
+
+// verify compares the offsets gl reports for the block with the std140
+// offsets computed from the Go struct
+func (ub *UniformBuffer) verify(program uint32, index uint32) error {
+	var dataSize, count int32
+	gl.GetActiveUniformBlockiv(program, index, gl.UNIFORM_BLOCK_DATA_SIZE, &dataSize)
+	gl.GetActiveUniformBlockiv(program, index, gl.UNIFORM_BLOCK_ACTIVE_UNIFORMS, &count)
+	if count == 0 {
+		return nil
+	}
+	indices := make([]int32, count)
+	gl.GetActiveUniformBlockiv(program, index, gl.UNIFORM_BLOCK_ACTIVE_UNIFORM_INDICES, &indices[0])
+	uniforms := make([]uint32, count)
+	for i, idx := range indices {
+		uniforms[i] = uint32(idx)
+	}
+	offsets := make([]int32, count)
+	strides := make([]int32, count)
+	gl.GetActiveUniformsiv(program, count, &uniforms[0], gl.UNIFORM_OFFSET, &offsets[0])
+	gl.GetActiveUniformsiv(program, count, &uniforms[0], gl.UNIFORM_ARRAY_STRIDE, &strides[0])
+
+	members := map[string]blockMember{}
+	for _, m := range ub.members {
+		members[m.name] = m
+	}
+	var maxLength int32
+	gl.GetProgramiv(program, gl.ACTIVE_UNIFORM_MAX_LENGTH, &maxLength)
+	buf := make([]uint8, maxLength+1)
+	for i, u := range uniforms {
+		var length int32
+		gl.GetActiveUniformName(program, u, int32(len(buf)), &length, &buf[0])
+		// members of blocks with an instance name are reported as Block.member
+		name := strings.TrimPrefix(string(buf[:length]), ub.Name+".")
+		m, ok := members[name]
+		switch {
+		case !ok:
+			return &BlockLayoutError{Block: ub.Name, Member: name, Message: fmt.Sprintf("no field in %v", ub.typ)}
+		case int(offsets[i]) != m.offset:
+			return &BlockLayoutError{Block: ub.Name, Member: name,
+				Message: fmt.Sprintf("offset is %d in the program but %d in %v", offsets[i], m.offset, ub.typ)}
+		case strides[i] != 0 && int(strides[i]) != m.stride:
+			return &BlockLayoutError{Block: ub.Name, Member: name,
+				Message: fmt.Sprintf("array stride is %d in the program but %d in %v", strides[i], m.stride, ub.typ)}
+		}
+	}
+	if int(dataSize) > len(ub.data) {
+		return &BlockLayoutError{Block: ub.Name,
+			Message: fmt.Sprintf("block is %d bytes in the program but %v is %d", dataSize, ub.typ, len(ub.data))}
+	}
+	return nil
+}
+
+// bindUniformBlocks binds each active block that has a uniform buffer to
+// the buffer's binding point, returning warnings for layout mismatches
+func (s *Shader) bindUniformBlocks() []Diagnostic {
+	var count, maxLength int32
+	gl.GetProgramiv(s.ID, gl.ACTIVE_UNIFORM_BLOCKS, &count)
+	gl.GetProgramiv(s.ID, gl.ACTIVE_UNIFORM_BLOCK_MAX_NAME_LENGTH, &maxLength)
+	var diags []Diagnostic
+	buf := make([]uint8, maxLength+1)
+	for i := uint32(0); i < uint32(count); i++ {
+		var length int32
+		gl.GetActiveUniformBlockName(s.ID, i, int32(len(buf)), &length, &buf[0])
+		ub, ok := uniformBuffers[string(buf[:length])]
+		if !ok {
+			continue
+		}
+		if err := ub.verify(s.ID, i); err != nil {
+			diags = append(diags, Diagnostic{Severity: SeverityWarning, Message: err.Error()})
+			continue
+		}
+		gl.UniformBlockBinding(s.ID, i, ub.Binding)
+	}
+	return diags
+}
+
+// std140Layout returns the size and base alignment of a type under std140
+func std140Layout(t reflect.Type) (size int, align int, err error) {
+	switch t {
+	case vec2Type:
+		return 8, 8, nil
+	case vec3Type:
+		return 12, 16, nil
+	case vec4Type:
+		return 16, 16, nil
+	case mat3Type:
+		// three vec3 columns, each padded to a vec4
+		return 48, 16, nil
+	case mat4Type:
+		return 64, 16, nil
+	}
+	switch t.Kind() {
+	case reflect.Float32, reflect.Int32, reflect.Uint32, reflect.Bool:
+		return 4, 4, nil
+	case reflect.Array:
+		elemSize, elemAlign, err := std140Layout(t.Elem())
+		if err != nil {
+			return 0, 0, err
+		}
+		stride := roundUp(elemSize, roundUp(elemAlign, 16))
+		return stride * t.Len(), roundUp(elemAlign, 16), nil
+	case reflect.Struct:
+		offset, maxAlign := 0, 4
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if fieldName(f) == "-" {
+				continue
+			}
+			size, align, err := std140Layout(f.Type)
+			if err != nil {
+				return 0, 0, fmt.Errorf("field %s: %v", f.Name, err)
+			}
+			offset = roundUp(offset, align) + size
+			if align > maxAlign {
+				maxAlign = align
+			}
+		}
+		align := roundUp(maxAlign, 16)
+		return roundUp(offset, align), align, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported type %v", t)
+}
+
+// flattenMembers lists the members of a struct the way gl names them
+func flattenMembers(t reflect.Type, prefix string, base int) []blockMember {
+	var members []blockMember
+	offset := 0
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := fieldName(f)
+		if name == "-" {
+			continue
+		}
+		size, align, _ := std140Layout(f.Type)
+		offset = roundUp(offset, align)
+		members = append(members, typeMembers(f.Type, prefix+name, base+offset)...)
+		offset += size
+	}
+	return members
+}
+
+func typeMembers(t reflect.Type, name string, offset int) []blockMember {
+	switch {
+	case t.Kind() == reflect.Struct:
+		return flattenMembers(t, name+".", offset)
+	case t.Kind() == reflect.Array && !isVectorOrMatrix(t):
+		elemSize, elemAlign, _ := std140Layout(t.Elem())
+		stride := roundUp(elemSize, roundUp(elemAlign, 16))
+		if t.Elem().Kind() == reflect.Struct {
+			// gl lists every element of an array of structs
+			var members []blockMember
+			for i := 0; i < t.Len(); i++ {
+				members = append(members, flattenMembers(t.Elem(), fmt.Sprintf("%s[%d].", name, i), offset+i*stride)...)
+			}
+			return members
+		}
+		return []blockMember{{name: name + "[0]", offset: offset, stride: stride}}
+	}
+	return []blockMember{{name: name, offset: offset}}
+}
+
+// encodeStd140 writes v to buf at offset
+func encodeStd140(buf []byte, offset int, v reflect.Value) {
+	t := v.Type()
+	switch t {
+	case vec2Type, vec3Type, vec4Type, mat4Type:
+		for i := 0; i < v.Len(); i++ {
+			putFloat(buf, offset+4*i, float32(v.Index(i).Float()))
+		}
+		return
+	case mat3Type:
+		for col := 0; col < 3; col++ {
+			for row := 0; row < 3; row++ {
+				putFloat(buf, offset+16*col+4*row, float32(v.Index(col*3+row).Float()))
+			}
+		}
+		return
+	}
+	switch t.Kind() {
+	case reflect.Float32:
+		putFloat(buf, offset, float32(v.Float()))
+	case reflect.Int32:
+		binary.LittleEndian.PutUint32(buf[offset:], uint32(v.Int()))
+	case reflect.Uint32:
+		binary.LittleEndian.PutUint32(buf[offset:], uint32(v.Uint()))
+	case reflect.Bool:
+		var b uint32
+		if v.Bool() {
+			b = 1
+		}
+		binary.LittleEndian.PutUint32(buf[offset:], b)
+	case reflect.Array:
+		elemSize, elemAlign, _ := std140Layout(t.Elem())
+		stride := roundUp(elemSize, roundUp(elemAlign, 16))
+		for i := 0; i < v.Len(); i++ {
+			encodeStd140(buf, offset+i*stride, v.Index(i))
+		}
+	case reflect.Struct:
+		fieldOffset := 0
+		for i := 0; i < t.NumField(); i++ {
+			if fieldName(t.Field(i)) == "-" {
+				continue
+			}
+			size, align, _ := std140Layout(t.Field(i).Type)
+			fieldOffset = roundUp(fieldOffset, align)
+			encodeStd140(buf, offset+fieldOffset, v.Field(i))
+			fieldOffset += size
+		}
+	}
+}
+
+// fieldName returns the block member name of a struct field
+func fieldName(f reflect.StructField) string {
+	if tag := f.Tag.Get("std140"); tag != "" {
+		return tag
+	}
+	return f.Name
+}
+
+func isVectorOrMatrix(t reflect.Type) bool {
+	return t == vec2Type || t == vec3Type || t == vec4Type || t == mat3Type || t == mat4Type
+}
+
+func putFloat(buf []byte, offset int, f float32) {
+	binary.LittleEndian.PutUint32(buf[offset:], math.Float32bits(f))
+}
+
+func roundUp(n int, align int) int {
+	return (n + align - 1) / align * align
+}
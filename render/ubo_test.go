@@ -0,0 +1,92 @@
+package render
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type testLight struct {
+	Position  mgl32.Vec3
+	Intensity float32
+}
+
+type testBlock struct {
+	Color   mgl32.Vec3
+	Alpha   float32
+	Weights [3]float32 `std140:"weights"`
+	Lights  [2]testLight
+	Normal  mgl32.Mat3
+	Enabled bool
+	Skipped int `std140:"-"`
+}
+
+func TestStd140Layout(t *testing.T) {
+	typ := reflect.TypeOf(testBlock{})
+	size, align, err := std140Layout(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 160 || align != 16 {
+		t.Errorf("size %d, align %d, want 160 and 16", size, align)
+	}
+	// a float packs after a vec3, scalar arrays and structs are padded to
+	// vec4 strides, and mat3 columns are padded to vec4s
+	want := []blockMember{
+		{name: "Color", offset: 0},
+		{name: "Alpha", offset: 12},
+		{name: "weights[0]", offset: 16, stride: 16},
+		{name: "Lights[0].Position", offset: 64},
+		{name: "Lights[0].Intensity", offset: 76},
+		{name: "Lights[1].Position", offset: 80},
+		{name: "Lights[1].Intensity", offset: 92},
+		{name: "Normal", offset: 96},
+		{name: "Enabled", offset: 144},
+	}
+	if got := flattenMembers(typ, "", 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("members:\n got %v\nwant %v", got, want)
+	}
+
+	if _, _, err := std140Layout(reflect.TypeOf(struct{ N int }{})); err == nil {
+		t.Error("int field: got no error")
+	}
+}
+
+func TestEncodeStd140(t *testing.T) {
+	block := testBlock{
+		Color:   mgl32.Vec3{1, 2, 3},
+		Alpha:   0.5,
+		Weights: [3]float32{4, 5, 6},
+		Lights:  [2]testLight{{mgl32.Vec3{7, 8, 9}, 10}, {mgl32.Vec3{11, 12, 13}, 14}},
+		Normal:  mgl32.Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9},
+		Enabled: true,
+		Skipped: 99,
+	}
+	buf := make([]byte, 160)
+	encodeStd140(buf, 0, reflect.ValueOf(block))
+
+	floats := map[int]float32{
+		0: 1, 4: 2, 8: 3, 12: 0.5,
+		16: 4, 32: 5, 48: 6,
+		64: 7, 68: 8, 72: 9, 76: 10,
+		80: 11, 84: 12, 88: 13, 92: 14,
+		96: 1, 100: 2, 104: 3, 112: 4, 116: 5, 120: 6, 128: 7, 132: 8, 136: 9,
+	}
+	for offset := 0; offset < 144; offset += 4 {
+		got := math.Float32frombits(binary.LittleEndian.Uint32(buf[offset:]))
+		if got != floats[offset] {
+			t.Errorf("float at %d is %v, want %v", offset, got, floats[offset])
+		}
+	}
+	if got := binary.LittleEndian.Uint32(buf[144:]); got != 1 {
+		t.Errorf("bool is %d, want 1", got)
+	}
+	for offset := 148; offset < len(buf); offset++ {
+		if buf[offset] != 0 {
+			t.Errorf("padding byte %d is %d", offset, buf[offset])
+		}
+	}
+}
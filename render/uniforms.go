@@ -0,0 +1,388 @@
+package render
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Uniform is an active uniform of a linked program
+type Uniform struct {
+	Name     string
+	Location int32
+	// Type is the gl type, e.g. gl.FLOAT_VEC3
+	Type uint32
+	// Size is the array length, 1 for non-arrays
+	Size int32
+}
+
+// uniform kinds, deciding which glUniform calls can set a type
+const (
+	floatKind = iota
+	intKind
+	uintKind
+	doubleKind
+	samplerKind
+	matrixKind
+	doubleMatrixKind
+)
+
+type uniformType struct {
+	name       string
+	kind       int
+	components int
+}
+
+var uniformTypes = map[uint32]uniformType{
+	gl.FLOAT:             {"float", floatKind, 1},
+	gl.FLOAT_VEC2:        {"vec2", floatKind, 2},
+	gl.FLOAT_VEC3:        {"vec3", floatKind, 3},
+	gl.FLOAT_VEC4:        {"vec4", floatKind, 4},
+	gl.INT:               {"int", intKind, 1},
+	gl.INT_VEC2:          {"ivec2", intKind, 2},
+	gl.INT_VEC3:          {"ivec3", intKind, 3},
+	gl.INT_VEC4:          {"ivec4", intKind, 4},
+	gl.UNSIGNED_INT:      {"uint", uintKind, 1},
+	gl.UNSIGNED_INT_VEC2: {"uvec2", uintKind, 2},
+	gl.UNSIGNED_INT_VEC3: {"uvec3", uintKind, 3},
+	gl.UNSIGNED_INT_VEC4: {"uvec4", uintKind, 4},
+	gl.BOOL:              {"bool", intKind, 1},
+	gl.BOOL_VEC2:         {"bvec2", intKind, 2},
+	gl.BOOL_VEC3:         {"bvec3", intKind, 3},
+	gl.BOOL_VEC4:         {"bvec4", intKind, 4},
+	gl.DOUBLE:            {"double", doubleKind, 1},
+	gl.DOUBLE_VEC2:       {"dvec2", doubleKind, 2},
+	gl.DOUBLE_VEC3:       {"dvec3", doubleKind, 3},
+	gl.DOUBLE_VEC4:       {"dvec4", doubleKind, 4},
+	gl.FLOAT_MAT2:        {"mat2", matrixKind, 4},
+	gl.FLOAT_MAT3:        {"mat3", matrixKind, 9},
+	gl.FLOAT_MAT4:        {"mat4", matrixKind, 16},
+	gl.FLOAT_MAT2x3:      {"mat2x3", matrixKind, 6},
+	gl.FLOAT_MAT2x4:      {"mat2x4", matrixKind, 8},
+	gl.FLOAT_MAT3x2:      {"mat3x2", matrixKind, 6},
+	gl.FLOAT_MAT3x4:      {"mat3x4", matrixKind, 12},
+	gl.FLOAT_MAT4x2:      {"mat4x2", matrixKind, 8},
+	gl.FLOAT_MAT4x3:      {"mat4x3", matrixKind, 12},
+	gl.DOUBLE_MAT2:       {"dmat2", doubleMatrixKind, 4},
+	gl.DOUBLE_MAT3:       {"dmat3", doubleMatrixKind, 9},
+	gl.DOUBLE_MAT4:       {"dmat4", doubleMatrixKind, 16},
+
+	gl.SAMPLER_1D:                                {"sampler1D", samplerKind, 1},
+	gl.SAMPLER_2D:                                {"sampler2D", samplerKind, 1},
+	gl.SAMPLER_3D:                                {"sampler3D", samplerKind, 1},
+	gl.SAMPLER_CUBE:                              {"samplerCube", samplerKind, 1},
+	gl.SAMPLER_1D_SHADOW:                         {"sampler1DShadow", samplerKind, 1},
+	gl.SAMPLER_2D_SHADOW:                         {"sampler2DShadow", samplerKind, 1},
+	gl.SAMPLER_CUBE_SHADOW:                       {"samplerCubeShadow", samplerKind, 1},
+	gl.SAMPLER_1D_ARRAY:                          {"sampler1DArray", samplerKind, 1},
+	gl.SAMPLER_2D_ARRAY:                          {"sampler2DArray", samplerKind, 1},
+	gl.SAMPLER_CUBE_MAP_ARRAY:                    {"samplerCubeArray", samplerKind, 1},
+	gl.SAMPLER_1D_ARRAY_SHADOW:                   {"sampler1DArrayShadow", samplerKind, 1},
+	gl.SAMPLER_2D_ARRAY_SHADOW:                   {"sampler2DArrayShadow", samplerKind, 1},
+	gl.SAMPLER_CUBE_MAP_ARRAY_SHADOW:             {"samplerCubeArrayShadow", samplerKind, 1},
+	gl.SAMPLER_2D_MULTISAMPLE:                    {"sampler2DMS", samplerKind, 1},
+	gl.SAMPLER_2D_MULTISAMPLE_ARRAY:              {"sampler2DMSArray", samplerKind, 1},
+	gl.SAMPLER_2D_RECT:                           {"sampler2DRect", samplerKind, 1},
+	gl.SAMPLER_2D_RECT_SHADOW:                    {"sampler2DRectShadow", samplerKind, 1},
+	gl.SAMPLER_BUFFER:                            {"samplerBuffer", samplerKind, 1},
+	gl.INT_SAMPLER_1D:                            {"isampler1D", samplerKind, 1},
+	gl.INT_SAMPLER_2D:                            {"isampler2D", samplerKind, 1},
+	gl.INT_SAMPLER_3D:                            {"isampler3D", samplerKind, 1},
+	gl.INT_SAMPLER_CUBE:                          {"isamplerCube", samplerKind, 1},
+	gl.INT_SAMPLER_1D_ARRAY:                      {"isampler1DArray", samplerKind, 1},
+	gl.INT_SAMPLER_2D_ARRAY:                      {"isampler2DArray", samplerKind, 1},
+	gl.INT_SAMPLER_CUBE_MAP_ARRAY:                {"isamplerCubeArray", samplerKind, 1},
+	gl.INT_SAMPLER_2D_MULTISAMPLE:                {"isampler2DMS", samplerKind, 1},
+	gl.INT_SAMPLER_2D_MULTISAMPLE_ARRAY:          {"isampler2DMSArray", samplerKind, 1},
+	gl.INT_SAMPLER_2D_RECT:                       {"isampler2DRect", samplerKind, 1},
+	gl.INT_SAMPLER_BUFFER:                        {"isamplerBuffer", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_1D:                   {"usampler1D", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_2D:                   {"usampler2D", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_3D:                   {"usampler3D", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_CUBE:                 {"usamplerCube", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_1D_ARRAY:             {"usampler1DArray", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_2D_ARRAY:             {"usampler2DArray", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_CUBE_MAP_ARRAY:       {"usamplerCubeArray", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_2D_MULTISAMPLE:       {"usampler2DMS", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_2D_MULTISAMPLE_ARRAY: {"usampler2DMSArray", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_2D_RECT:              {"usampler2DRect", samplerKind, 1},
+	gl.UNSIGNED_INT_SAMPLER_BUFFER:               {"usamplerBuffer", samplerKind, 1},
+}
+
+// typeName returns the GLSL name of a gl uniform type
+func typeName(xtype uint32) string {
+	if t, ok := uniformTypes[xtype]; ok {
+		return t.name
+	}
+	return fmt.Sprintf("type(0x%x)", xtype)
+}
+
+// reflectUniforms caches the location and type of every active uniform.
+// Arrays are listed under their plain name as well as name[0], and each
+// element under name[i], with the number of elements left from there as
+// its Size. Name is always the name of the active uniform.
+func (s *Shader) reflectUniforms() {
+	s.uniforms = map[string]Uniform{}
+	s.reported = map[string]bool{}
+	s.values = map[string]uniformValue{}
+	var count, maxLength int32
+	gl.GetProgramiv(s.ID, gl.ACTIVE_UNIFORMS, &count)
+	gl.GetProgramiv(s.ID, gl.ACTIVE_UNIFORM_MAX_LENGTH, &maxLength)
+	if maxLength == 0 {
+		return
+	}
+	buf := make([]uint8, maxLength)
+	for i := uint32(0); i < uint32(count); i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetActiveUniform(s.ID, i, maxLength, &length, &size, &xtype, &buf[0])
+		name := string(buf[:length])
+		location := gl.GetUniformLocation(s.ID, gl.Str(name+"\x00"))
+		if location < 0 {
+			// members of uniform blocks have no location
+			continue
+		}
+		u := Uniform{Name: name, Location: location, Type: xtype, Size: size}
+		s.uniforms[name] = u
+		if !strings.HasSuffix(name, "[0]") {
+			continue
+		}
+		base := strings.TrimSuffix(name, "[0]")
+		s.uniforms[base] = u
+		// element locations are not guaranteed to be consecutive
+		for j := int32(1); j < size; j++ {
+			element := fmt.Sprintf("%s[%d]", base, j)
+			location := gl.GetUniformLocation(s.ID, gl.Str(element+"\x00"))
+			if location >= 0 {
+				s.uniforms[element] = Uniform{Name: name, Location: location, Type: xtype, Size: size - j}
+			}
+		}
+	}
+}
+
+// Uniforms returns the active uniforms of the program
+func (s *Shader) Uniforms() []Uniform {
+	var uniforms []Uniform
+	for name, u := range s.uniforms {
+		if name == u.Name {
+			uniforms = append(uniforms, u)
+		}
+	}
+	return uniforms
+}
+
+// Uniform returns the named active uniform
+func (s *Shader) Uniform(name string) (Uniform, bool) {
+	u, ok := s.uniforms[name]
+	return u, ok
+}
+
+// location returns the location of the named uniform if it is active and
+// of the given kind. Otherwise the problem is logged, once per name.
+func (s *Shader) location(name string, components int, kind int) (int32, bool) {
+	u, ok := s.uniforms[name]
+	if !ok {
+		s.report(name, "uniform '%s' is not active in the program", name)
+		return -1, false
+	}
+	if t := uniformTypes[u.Type]; t.kind == kind && t.components == components {
+		return u.Location, true
+	}
+	s.report(name, "uniform '%s' is %s and can't be set with that setter", name, typeName(u.Type))
+	return -1, false
+}
+
+func (s *Shader) report(name string, format string, args ...interface{}) {
+	if s.reported[name] {
+		return
+	}
+	s.reported[name] = true
+	log.Printf(format, args...)
+}
+
+// uniformValue is the last value set for a uniform, kept so it can be
+// reapplied to a reloaded program
+type uniformValue struct {
+	kind       int
+	components int
+	set        func(location int32)
+}
+
+// set applies a value if the named uniform is active and of the right type,
+// and remembers it
+func (s *Shader) set(name string, v uniformValue) {
+	location, ok := s.location(name, v.components, v.kind)
+	if !ok {
+		return
+	}
+	v.set(location)
+	s.values[name] = v
+}
+
+// restoreUniforms reapplies the values set on old to the current program,
+// logging the uniforms that no longer exist or changed type
+func (s *Shader) restoreUniforms(old *Shader) {
+	for _, name := range sortedNames(old.values) {
+		v := old.values[name]
+		u, ok := s.uniforms[name]
+		if !ok {
+			log.Printf("uniform '%s' was removed from the reloaded program", name)
+			continue
+		}
+		if t := uniformTypes[u.Type]; t.kind != v.kind || t.components != v.components {
+			log.Printf("uniform '%s' changed type to %s in the reloaded program", name, typeName(u.Type))
+			continue
+		}
+		v.set(u.Location)
+		s.values[name] = v
+	}
+}
+
+func sortedNames(values map[string]uniformValue) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The setters below set a uniform of the current program, so the program
+// must be in use. Unknown names and type mismatches are logged once per
+// name and otherwise ignored.
+
+// SetFloat sets a float uniform
+func (s *Shader) SetFloat(name string, value float32) {
+	s.set(name, uniformValue{floatKind, 1, func(location int32) {
+		gl.Uniform1f(location, value)
+	}})
+}
+
+// SetVec2 sets a vec2 uniform
+func (s *Shader) SetVec2(name string, value mgl32.Vec2) {
+	s.set(name, uniformValue{floatKind, 2, func(location int32) {
+		gl.Uniform2fv(location, 1, &value[0])
+	}})
+}
+
+// SetVec3 sets a vec3 uniform
+func (s *Shader) SetVec3(name string, value mgl32.Vec3) {
+	s.set(name, uniformValue{floatKind, 3, func(location int32) {
+		gl.Uniform3fv(location, 1, &value[0])
+	}})
+}
+
+// SetVec4 sets a vec4 uniform
+func (s *Shader) SetVec4(name string, value mgl32.Vec4) {
+	s.set(name, uniformValue{floatKind, 4, func(location int32) {
+		gl.Uniform4fv(location, 1, &value[0])
+	}})
+}
+
+// SetInt sets an int or bool uniform
+func (s *Shader) SetInt(name string, value int32) {
+	s.set(name, uniformValue{intKind, 1, func(location int32) {
+		gl.Uniform1i(location, value)
+	}})
+}
+
+// SetBool sets a bool uniform
+func (s *Shader) SetBool(name string, value bool) {
+	var v int32
+	if value {
+		v = 1
+	}
+	s.SetInt(name, v)
+}
+
+// SetIVec2 sets an ivec2 uniform
+func (s *Shader) SetIVec2(name string, value [2]int32) {
+	s.set(name, uniformValue{intKind, 2, func(location int32) {
+		gl.Uniform2iv(location, 1, &value[0])
+	}})
+}
+
+// SetIVec3 sets an ivec3 uniform
+func (s *Shader) SetIVec3(name string, value [3]int32) {
+	s.set(name, uniformValue{intKind, 3, func(location int32) {
+		gl.Uniform3iv(location, 1, &value[0])
+	}})
+}
+
+// SetIVec4 sets an ivec4 uniform
+func (s *Shader) SetIVec4(name string, value [4]int32) {
+	s.set(name, uniformValue{intKind, 4, func(location int32) {
+		gl.Uniform4iv(location, 1, &value[0])
+	}})
+}
+
+// SetUint sets a uint uniform
+func (s *Shader) SetUint(name string, value uint32) {
+	s.set(name, uniformValue{uintKind, 1, func(location int32) {
+		gl.Uniform1ui(location, value)
+	}})
+}
+
+// SetUVec2 sets a uvec2 uniform
+func (s *Shader) SetUVec2(name string, value [2]uint32) {
+	s.set(name, uniformValue{uintKind, 2, func(location int32) {
+		gl.Uniform2uiv(location, 1, &value[0])
+	}})
+}
+
+// SetUVec3 sets a uvec3 uniform
+func (s *Shader) SetUVec3(name string, value [3]uint32) {
+	s.set(name, uniformValue{uintKind, 3, func(location int32) {
+		gl.Uniform3uiv(location, 1, &value[0])
+	}})
+}
+
+// SetUVec4 sets a uvec4 uniform
+func (s *Shader) SetUVec4(name string, value [4]uint32) {
+	s.set(name, uniformValue{uintKind, 4, func(location int32) {
+		gl.Uniform4uiv(location, 1, &value[0])
+	}})
+}
+
+// SetMat3 sets a mat3 uniform
+func (s *Shader) SetMat3(name string, value mgl32.Mat3) {
+	s.set(name, uniformValue{matrixKind, 9, func(location int32) {
+		gl.UniformMatrix3fv(location, 1, false, &value[0])
+	}})
+}
+
+// SetMat4 sets a mat4 uniform
+func (s *Shader) SetMat4(name string, value mgl32.Mat4) {
+	s.set(name, uniformValue{matrixKind, 16, func(location int32) {
+		gl.UniformMatrix4fv(location, 1, false, &value[0])
+	}})
+}
+
+// SetMat4Array sets the first len(values) elements of a mat4 array
+// uniform, e.g. the joint matrices of a skin. Nothing is set if there are
+// more values than elements.
+func (s *Shader) SetMat4Array(name string, values []mgl32.Mat4) {
+	if len(values) == 0 {
+		return
+	}
+	if u, ok := s.uniforms[name]; ok && len(values) > int(u.Size) {
+		s.report(name, "uniform '%s' has %d elements, can't set %d", name, u.Size, len(values))
+		return
+	}
+	values = append([]mgl32.Mat4(nil), values...)
+	s.set(name, uniformValue{matrixKind, 16, func(location int32) {
+		gl.UniformMatrix4fv(location, int32(len(values)), false, &values[0][0])
+	}})
+}
+
+// SetSampler points a sampler uniform at a texture unit, e.g. 0 for
+// gl.TEXTURE0
+func (s *Shader) SetSampler(name string, unit int32) {
+	s.set(name, uniformValue{samplerKind, 1, func(location int32) {
+		gl.Uniform1i(location, unit)
+	}})
+}
@@ -0,0 +1,162 @@
+package render
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Defines are preprocessor macros injected into a shader, e.g.
+// Defines{"TWO_SAMPLERS": "", "MIX": "0.2"}
+type Defines map[string]string
+
+// names returns the macro names in sorted order
+func (d Defines) names() []string {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// key returns the defines in a canonical form
+func (d Defines) key() string {
+	names := d.names()
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + d[name]
+	}
+	return strings.Join(parts, ";")
+}
+
+// WithDefines returns a copy of the source with a #define for each entry
+// inserted after the #version line. A #line directive follows the defines
+// so diagnostics keep their original line numbers.
+func (src *Source) WithDefines(defines Defines) *Source {
+	if len(defines) == 0 {
+		return src
+	}
+	lines := strings.Split(strings.TrimRight(src.Text, "\x00"), "\n")
+	at := 0
+	for i, line := range lines {
+		if versionDirective.MatchString(line) {
+			at = i + 1
+			break
+		}
+	}
+
+	var inserted []string
+	var locations []location
+	for _, name := range defines.names() {
+		inserted = append(inserted, strings.TrimSpace("#define "+name+" "+defines[name]))
+		locations = append(locations, location{})
+	}
+	// the line after the insertion point, in the numbering of its own file
+	if at < len(src.locations) && src.locations[at].line != 0 {
+		loc := src.locations[at]
+		inserted = append(inserted, fmt.Sprintf("#line %d %d", loc.line, loc.file))
+		locations = append(locations, location{})
+	}
+
+	out := *src
+	out.Text = strings.Join(append(append(append([]string(nil), lines[:at]...), inserted...), lines[at:]...), "\n") + "\x00"
+	out.locations = append(append(append([]location(nil), src.locations[:at]...), locations...), src.locations[at:]...)
+	return &out
+}
+
+// Variants builds and caches programs compiled from the same shader files
+// with different sets of defines. Cached programs are keyed by the hash of
+// the preprocessed sources and the define set, and are deleted when the
+// sources change. Variants is an Asset, so it can be registered with
+// Assets to pick up source changes.
+type Variants struct {
+	shaders *Shaders
+	sources map[Stage]*Source
+	hash    string
+	cache   map[string]variant
+}
+
+// a cached build; failed builds are cached too so they are not retried
+// every frame until the sources change
+type variant struct {
+	shader *Shader
+	err    error
+}
+
+// NewVariants reads the given shader files
+func NewVariants(shaders *Shaders) (*Variants, error) {
+	v := &Variants{
+		shaders: shaders,
+		cache:   map[string]variant{},
+	}
+	if err := v.Reload(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Get returns the program built with the given defines, building it on
+// first use
+func (v *Variants) Get(defines Defines) (*Shader, error) {
+	key := v.hash + "/" + defines.key()
+	if cached, ok := v.cache[key]; ok {
+		return cached.shader, cached.err
+	}
+	sources := map[Stage]*Source{}
+	for stage, src := range v.sources {
+		sources[stage] = src.WithDefines(defines)
+	}
+	shader, err := NewShaderFromSources(sources)
+	v.cache[key] = variant{shader: shader, err: err}
+	return shader, err
+}
+
+// Update re-reads the sources if the shaders' watcher reported a change
+func (v *Variants) Update() error {
+	updated, sources, err := v.shaders.GetUpdatedSource()
+	if err != nil || !updated {
+		return err
+	}
+	v.setSources(sources)
+	return nil
+}
+
+// Paths returns the shader files and the files they include
+func (v *Variants) Paths() []string {
+	return v.shaders.Deps()
+}
+
+// Reload re-reads the sources, dropping every cached program if they changed
+func (v *Variants) Reload() error {
+	sources, err := v.shaders.GetSource()
+	if err != nil {
+		return err
+	}
+	v.setSources(sources)
+	return nil
+}
+
+// Invalidate deletes every cached program
+func (v *Variants) Invalidate() {
+	for key, cached := range v.cache {
+		if cached.shader != nil {
+			cached.shader.Delete()
+		}
+		delete(v.cache, key)
+	}
+}
+
+func (v *Variants) setSources(sources map[Stage]*Source) {
+	h := sha1.New()
+	for _, stage := range sortedStages(sources) {
+		fmt.Fprintf(h, "%s\n%s", stage, sources[stage].Text)
+	}
+	hash := fmt.Sprintf("%x", h.Sum(nil))
+	if hash == v.hash {
+		return
+	}
+	v.Invalidate()
+	v.sources, v.hash = sources, hash
+}
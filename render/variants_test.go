@@ -0,0 +1,58 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithDefines(t *testing.T) {
+	src, err := DefaultPreprocessor.ProcessSource("shader.frag", "// header\n#version 330 core\nout vec4 c;\nvoid main() {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same := src.WithDefines(nil); same != src {
+		t.Error("no defines: got a copy, want the same source")
+	}
+
+	out := src.WithDefines(Defines{"TWO_SAMPLERS": "", "MIX": "0.2"})
+	lines := strings.Split(strings.TrimRight(out.Text, "\x00"), "\n")
+	want := []string{"// header", "#version 330 core", "#define MIX 0.2", "#define TWO_SAMPLERS", "#line 3 0", "out vec4 c;"}
+	for i, line := range want {
+		if i >= len(lines) || lines[i] != line {
+			t.Fatalf("got\n%s\nwant it to start with\n%s", out.Text, strings.Join(want, "\n"))
+		}
+	}
+	for i, line := range lines {
+		if line == "void main() {}" {
+			if file, n := out.locate(i + 1); file != "shader.frag" || n != 4 {
+				t.Errorf("main is at %s:%d, want shader.frag:4", file, n)
+			}
+		}
+	}
+	if strings.Contains(src.Text, "#define") {
+		t.Errorf("the original source was changed:\n%s", src.Text)
+	}
+}
+
+func TestDefinesKey(t *testing.T) {
+	a := Defines{}
+	a["MIX"] = "0.2"
+	a["TWO_SAMPLERS"] = ""
+	b := Defines{"TWO_SAMPLERS": "", "MIX": "0.2"}
+	if a.key() != "MIX=0.2;TWO_SAMPLERS=" || a.key() != b.key() {
+		t.Errorf("keys %q and %q, want both MIX=0.2;TWO_SAMPLERS=", a.key(), b.key())
+	}
+	if c := (Defines{"MIX": "0.3", "TWO_SAMPLERS": ""}); c.key() == a.key() {
+		t.Errorf("different values share the key %q", c.key())
+	}
+}
+
+func TestVariantsReuse(t *testing.T) {
+	shader := &Shader{}
+	v := &Variants{hash: "h", cache: map[string]variant{}}
+	v.cache["h/"+Defines{"MIX": "0.2", "TWO_SAMPLERS": ""}.key()] = variant{shader: shader}
+	got, err := v.Get(Defines{"TWO_SAMPLERS": "", "MIX": "0.2"})
+	if err != nil || got != shader {
+		t.Errorf("got %p, %v, want the cached variant %p", got, err, shader)
+	}
+}
@@ -0,0 +1,239 @@
+package render
+
+import (
+	"crypto/sha1"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long a Watcher waits for a file to settle after an
+// event before reading it. Editors often write a file in several steps.
+const DefaultDebounce = 100 * time.Millisecond
+
+// Change is sent to subscribers when the contents of a watched file change
+type Change struct {
+	Path string
+}
+
+// Watcher notifies subscribers when the contents of files change. It watches
+// the directories containing the files rather than the files themselves, so
+// editors that save by writing a temporary file and renaming it over the
+// original are picked up, and a file that is briefly missing during a save
+// is not an error. Saves that leave the contents unchanged are ignored.
+//
+// Subscribers are notified from the watcher's goroutines; gl work should be
+// done on the main thread, e.g. by receiving from a channel each frame.
+type Watcher struct {
+	fs       *fsnotify.Watcher
+	debounce time.Duration
+
+	mu    sync.Mutex
+	files map[string]*watchedFile
+	dirs  map[string]int
+	done  chan struct{}
+}
+
+type watchedFile struct {
+	hash  [sha1.Size]byte
+	subs  []subscriber
+	timer *time.Timer
+}
+
+// subscriber is a channel or function subscribed to a file, under the path
+// it was subscribed with, which is the Path of the changes it receives
+type subscriber struct {
+	path string
+	ch   chan<- Change
+	fn   func(Change)
+}
+
+// NewWatcher starts a watcher that waits debounce after the last event on a
+// file before checking it. A zero debounce uses DefaultDebounce.
+func NewWatcher(debounce time.Duration) (*Watcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if debounce == 0 {
+		debounce = DefaultDebounce
+	}
+	w := &Watcher{
+		fs:       fs,
+		debounce: debounce,
+		files:    map[string]*watchedFile{},
+		dirs:     map[string]int{},
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Watch sends a Change on ch each time the file at path changes. Sends never
+// block; if ch is full the change is dropped, since a pending change already
+// tells the receiver to reload. Several files may share one channel.
+func (w *Watcher) Watch(path string, ch chan<- Change) error {
+	return w.subscribe(subscriber{path: path, ch: ch})
+}
+
+// OnChange calls fn each time the file at path changes
+func (w *Watcher) OnChange(path string, fn func(Change)) error {
+	return w.subscribe(subscriber{path: path, fn: fn})
+}
+
+// Unwatch stops sending changes to the file at path on ch. Other
+// subscriptions on ch, and on the same file under another path, are kept.
+// The file's directory is no longer watched once nothing in it is.
+func (w *Watcher) Unwatch(path string, ch chan<- Change) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	f, ok := w.files[abs]
+	if !ok {
+		return
+	}
+	subs := f.subs[:0]
+	for _, s := range f.subs {
+		if s.ch != ch || filepath.Clean(s.path) != filepath.Clean(path) {
+			subs = append(subs, s)
+		}
+	}
+	f.subs = subs
+	if len(f.subs) == 0 {
+		w.remove(abs, f)
+	}
+}
+
+// Close stops the watcher
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	for _, f := range w.files {
+		if f.timer != nil {
+			f.timer.Stop()
+		}
+	}
+	w.mu.Unlock()
+	close(w.done)
+	return w.fs.Close()
+}
+
+func (w *Watcher) subscribe(s subscriber) error {
+	abs, err := filepath.Abs(s.path)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if f, ok := w.files[abs]; ok {
+		f.subs = append(f.subs, s)
+		return nil
+	}
+
+	buf, err := ioutil.ReadFile(abs)
+	if os.IsNotExist(err) {
+		return &FileNotFoundError{Path: s.path}
+	}
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(abs)
+	if w.dirs[dir] == 0 {
+		if err := w.fs.Add(dir); err != nil {
+			return err
+		}
+	}
+	w.dirs[dir]++
+	w.files[abs] = &watchedFile{
+		hash: sha1.Sum(buf),
+		subs: []subscriber{s},
+	}
+	return nil
+}
+
+// remove stops watching a file. w.mu must be held.
+func (w *Watcher) remove(abs string, f *watchedFile) {
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	delete(w.files, abs)
+	dir := filepath.Dir(abs)
+	w.dirs[dir]--
+	if w.dirs[dir] == 0 {
+		delete(w.dirs, dir)
+		w.fs.Remove(dir)
+	}
+}
+
+func (w *Watcher) run() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			w.touch(filepath.Clean(event.Name))
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			log.Println("file watcher:", err)
+		}
+	}
+}
+
+// touch restarts the debounce timer of a watched file
+func (w *Watcher) touch(abs string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	f, ok := w.files[abs]
+	if !ok {
+		return
+	}
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	f.timer = time.AfterFunc(w.debounce, func() { w.check(abs) })
+}
+
+// check notifies subscribers if the file's contents have changed since the
+// last check. A file that is missing is left alone; if it comes back it
+// will be checked again.
+func (w *Watcher) check(abs string) {
+	buf, err := ioutil.ReadFile(abs)
+	if err != nil {
+		return
+	}
+	hash := sha1.Sum(buf)
+
+	w.mu.Lock()
+	f, ok := w.files[abs]
+	if !ok || f.hash == hash {
+		w.mu.Unlock()
+		return
+	}
+	f.hash = hash
+	subs := append([]subscriber(nil), f.subs...)
+	w.mu.Unlock()
+
+	for _, s := range subs {
+		change := Change{Path: s.path}
+		if s.fn != nil {
+			s.fn(change)
+			continue
+		}
+		select {
+		case s.ch <- change:
+		default:
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package render
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestWatcher(t *testing.T) *Watcher {
+	t.Helper()
+	w, err := NewWatcher(20 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+func writeFile(t *testing.T, path string, text string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectChange waits for a change to path on ch
+func expectChange(t *testing.T, ch <-chan Change, path string) {
+	t.Helper()
+	select {
+	case change := <-ch:
+		if change.Path != path {
+			t.Errorf("change to %s, want %s", change.Path, path)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no change to %s", path)
+	}
+}
+
+// expectNone checks that nothing arrives on ch for a while
+func expectNone(t *testing.T, ch <-chan Change) {
+	t.Helper()
+	select {
+	case change := <-ch:
+		t.Errorf("unexpected change to %s", change.Path)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestWatcherDebounce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shader.frag")
+	writeFile(t, path, "a")
+	w := newTestWatcher(t)
+	ch := make(chan Change, 10)
+	if err := w.Watch(path, ch); err != nil {
+		t.Fatal(err)
+	}
+
+	// a save in several steps is one change
+	writeFile(t, path, "")
+	writeFile(t, path, "b")
+	writeFile(t, path, "bc")
+	expectChange(t, ch, path)
+	expectNone(t, ch)
+
+	// saves that leave the contents unchanged are ignored, even if the
+	// file is rewritten in between
+	writeFile(t, path, "bc")
+	expectNone(t, ch)
+	writeFile(t, path, "")
+	writeFile(t, path, "bc")
+	expectNone(t, ch)
+
+	writeFile(t, path, "d")
+	expectChange(t, ch, path)
+}
+
+func TestWatcherUnwatch(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.glsl")
+	b := filepath.Join(dir, "b.glsl")
+	writeFile(t, a, "a")
+	writeFile(t, b, "b")
+	w := newTestWatcher(t)
+	ch := make(chan Change, 10)
+	kept := make(chan Change, 10)
+	for _, sub := range []struct {
+		path string
+		ch   chan Change
+	}{{a, ch}, {b, ch}, {a, kept}} {
+		if err := w.Watch(sub.path, sub.ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// only the subscription of ch to a is removed
+	w.Unwatch(a, ch)
+	writeFile(t, a, "a2")
+	expectChange(t, kept, a)
+	expectNone(t, ch)
+	writeFile(t, b, "b2")
+	expectChange(t, ch, b)
+
+	w.Unwatch(b, ch)
+	w.Unwatch(a, kept)
+	w.mu.Lock()
+	if len(w.files) != 0 || len(w.dirs) != 0 {
+		t.Errorf("still watching %d files in %d directories", len(w.files), len(w.dirs))
+	}
+	w.mu.Unlock()
+	writeFile(t, a, "a3")
+	writeFile(t, b, "b3")
+	expectNone(t, ch)
+	expectNone(t, kept)
+}
@@ -0,0 +1,299 @@
+package render
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// Backend is a way of creating the gl context of a Window
+type Backend string
+
+const (
+	// GLFWBackend opens a glfw window on the desktop
+	GLFWBackend Backend = "glfw"
+	// EGLBackend renders into an offscreen framebuffer without a display,
+	// e.g. with Mesa's llvmpipe on a build machine. It is only built on
+	// linux with -tags egl, which also loads gl functions through EGL, so
+	// other builds need neither cgo nor libEGL.
+	EGLBackend Backend = "egl"
+)
+
+// SelectedBackend returns the backend NewWindow uses: the -backend flag if
+// set, else the RENDER_BACKEND environment variable, else GLFWBackend
+func SelectedBackend() Backend {
+	if settings.backend != "" {
+		return Backend(settings.backend)
+	}
+	if env := os.Getenv("RENDER_BACKEND"); env != "" {
+		return Backend(env)
+	}
+	return GLFWBackend
+}
+
+// Window is a window or offscreen framebuffer with a current gl 3.3 core
+// context. glfw must be used from the main OS thread, so callers should
+// runtime.LockOSThread in an init function.
+//
+// A headless window has no glfw window: it draws into its own framebuffer,
+// reports no input and closes after MaxFrames frames.
+type Window struct {
+	// Window is nil for a headless window
+	*glfw.Window
+
+	// MaxFrames closes the window after that many frames, if positive
+	MaxFrames int
+
+	headless    *eglContext
+	framebuffer *framebuffer
+	frame       int
+	closed      bool
+	title       string
+	captureNext bool
+
+	resize    []func(width int, height int)
+	wireframe bool
+}
+
+// NewWindow opens a window with the selected backend (see SelectedBackend)
+// and initializes gl for it
+func NewWindow(width int, height int, title string) (*Window, error) {
+	switch backend := SelectedBackend(); backend {
+	case GLFWBackend:
+		return newGLFWWindow(width, height, title)
+	case EGLBackend:
+		return NewHeadlessWindow(width, height, title)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", backend)
+	}
+}
+
+func newGLFWWindow(width int, height int, title string) (*Window, error) {
+	// initialize glfw window
+	if err := glfw.Init(); err != nil {
+		return nil, fmt.Errorf("initializing glfw: %v", err)
+	}
+
+	glfw.WindowHint(glfw.ContextVersionMajor, 3)
+	glfw.WindowHint(glfw.ContextVersionMinor, 3)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	window, err := glfw.CreateWindow(width, height, title, nil, nil)
+	if err != nil {
+		glfw.Terminate()
+		return nil, fmt.Errorf("creating window: %v", err)
+	}
+	window.MakeContextCurrent()
+	w := &Window{
+		Window:    window,
+		MaxFrames: settings.frames,
+		title:     title,
+	}
+	window.SetFramebufferSizeCallback(w.fbcallback)
+
+	// init Glow
+	if err := gl.Init(); err != nil {
+		glfw.Terminate()
+		return nil, fmt.Errorf("initializing gl: %v", err)
+	}
+
+	return w, nil
+}
+
+// NewHeadlessWindow creates a gl context through EGL with no display and
+// binds an offscreen framebuffer of the given size for drawing. It runs for
+// the number of frames given by the -frames flag, or a single frame.
+func NewHeadlessWindow(width int, height int, title string) (*Window, error) {
+	ctx, err := newEGLContext()
+	if err != nil {
+		return nil, fmt.Errorf("creating headless context: %v", err)
+	}
+	if err := gl.Init(); err != nil {
+		ctx.destroy()
+		return nil, fmt.Errorf("initializing gl: %v", err)
+	}
+	fb, err := newFramebuffer(int32(width), int32(height))
+	if err != nil {
+		ctx.destroy()
+		return nil, err
+	}
+	w := &Window{
+		MaxFrames:   settings.frames,
+		headless:    ctx,
+		framebuffer: fb,
+		title:       title,
+	}
+	if w.MaxFrames <= 0 {
+		w.MaxFrames = 1
+	}
+	// run long enough to capture the frame asked for
+	if w.MaxFrames < settings.captureFrame {
+		w.MaxFrames = settings.captureFrame
+	}
+	return w, nil
+}
+
+// Headless reports whether the window draws offscreen
+func (w *Window) Headless() bool {
+	return w.Window == nil
+}
+
+// OnResize registers fn to be called with the new framebuffer size when the
+// window is resized, e.g. Camera.SetViewport
+func (w *Window) OnResize(fn func(width int, height int)) {
+	w.resize = append(w.resize, fn)
+}
+
+// Version returns the gl version string of the window's context
+func (w *Window) Version() string {
+	return gl.GoStr(gl.GetString(gl.VERSION))
+}
+
+// PrintVersion prints the gl version of the window's context
+func (w *Window) PrintVersion() {
+	fmt.Println("OpenGL version", w.Version())
+}
+
+// Frame returns the number of frames shown so far
+func (w *Window) Frame() int {
+	return w.frame
+}
+
+// Update saves a screenshot if one is due, then swaps the buffers and polls
+// for events
+func (w *Window) Update() {
+	w.capture()
+	w.frame++
+	if w.Headless() {
+		gl.Flush()
+		return
+	}
+	w.SwapBuffers()
+	glfw.PollEvents()
+}
+
+// ToggleWireframe switches between drawing filled polygons and outlines
+func (w *Window) ToggleWireframe() {
+	w.wireframe = !w.wireframe
+	if w.wireframe {
+		gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
+	} else {
+		gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+	}
+}
+
+// Destroy flushes gl and terminates glfw, or frees the offscreen context
+func (w *Window) Destroy() {
+	gl.Flush()
+	if w.Headless() {
+		w.framebuffer.delete()
+		w.headless.destroy()
+		return
+	}
+	glfw.Terminate()
+}
+
+func (w *Window) fbcallback(_ *glfw.Window, width int, height int) {
+	gl.Viewport(0, 0, int32(width), int32(height))
+	for _, fn := range w.resize {
+		fn(width, height)
+	}
+}
+
+// The methods below shadow the glfw.Window methods used by the chapters and
+// the input helpers, so they also work on a headless window.
+
+// ShouldClose reports whether the window was asked to close or has shown
+// MaxFrames frames
+func (w *Window) ShouldClose() bool {
+	if w.MaxFrames > 0 && w.frame >= w.MaxFrames {
+		return true
+	}
+	if w.Headless() {
+		return w.closed
+	}
+	return w.Window.ShouldClose()
+}
+
+// SetShouldClose asks the window to close
+func (w *Window) SetShouldClose(value bool) {
+	if w.Headless() {
+		w.closed = value
+		return
+	}
+	w.Window.SetShouldClose(value)
+}
+
+// SetTitle sets the window title
+func (w *Window) SetTitle(title string) {
+	w.title = title
+	if !w.Headless() {
+		w.Window.SetTitle(title)
+	}
+}
+
+// Title returns the window title
+func (w *Window) Title() string {
+	return w.title
+}
+
+// GetFramebufferSize returns the size of the framebuffer in pixels
+func (w *Window) GetFramebufferSize() (width int, height int) {
+	if w.Headless() {
+		return int(w.framebuffer.width), int(w.framebuffer.height)
+	}
+	return w.Window.GetFramebufferSize()
+}
+
+// GetCursorPos returns the cursor position, always 0, 0 when headless
+func (w *Window) GetCursorPos() (x float64, y float64) {
+	if w.Headless() {
+		return 0, 0
+	}
+	return w.Window.GetCursorPos()
+}
+
+// GetKey returns the last state of key, always released when headless
+func (w *Window) GetKey(key glfw.Key) glfw.Action {
+	if w.Headless() {
+		return glfw.Release
+	}
+	return w.Window.GetKey(key)
+}
+
+// GetMouseButton returns the last state of button, always released when
+// headless
+func (w *Window) GetMouseButton(button glfw.MouseButton) glfw.Action {
+	if w.Headless() {
+		return glfw.Release
+	}
+	return w.Window.GetMouseButton(button)
+}
+
+// SetKeyCallback sets the key callback, which is never called when headless
+func (w *Window) SetKeyCallback(cb glfw.KeyCallback) glfw.KeyCallback {
+	if w.Headless() {
+		return nil
+	}
+	return w.Window.SetKeyCallback(cb)
+}
+
+// SetMouseButtonCallback sets the mouse button callback, which is never
+// called when headless
+func (w *Window) SetMouseButtonCallback(cb glfw.MouseButtonCallback) glfw.MouseButtonCallback {
+	if w.Headless() {
+		return nil
+	}
+	return w.Window.SetMouseButtonCallback(cb)
+}
+
+// SetScrollCallback sets the scroll callback, which is never called when
+// headless
+func (w *Window) SetScrollCallback(cb glfw.ScrollCallback) glfw.ScrollCallback {
+	if w.Headless() {
+		return nil
+	}
+	return w.Window.SetScrollCallback(cb)
+}